@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// Add resolves deps (server/owner/repo[@version]), adds them to the configuration
+// file in dir and updates the lock file.  A dependency already in the
+// configuration is replaced.  Other dependencies keep their locked version
+func (o *Engine) Add(dir string, deps []string) ([]CoordChange, error) {
+	status, err := o.editableConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := configDepsByName(status.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// resolve before touching the configuration file
+	pinned := make(map[string]*model.LockedCoord)
+	for _, dep := range deps {
+		fc, err := model.NewCoordFromStr(dep)
+		if err != nil {
+			return nil, err
+		}
+		lc, err := o.resolveCoord(fc)
+		if err != nil {
+			return nil, err
+		}
+		pinned[fc.CanonicalNameNoVersion()] = lc
+	}
+
+	return o.editConfigAndRelock(dir, status, nil, pinned, func() error {
+		var err error
+		for _, dep := range deps {
+			fc, _ := model.NewCoordFromStr(dep)
+			name := fc.CanonicalNameNoVersion()
+			old, ok := existing[name]
+			switch {
+			case ok && old == dep:
+				continue
+			case ok:
+				err = utils.ReplaceConfigDep(status.ConfigFile, old, dep)
+			default:
+				err = utils.AddConfigDep(status.ConfigFile, dep)
+			}
+			if err != nil {
+				return err
+			}
+			existing[name] = dep
+		}
+		return nil
+	})
+}
+
+// Remove removes the dependencies in names (server/owner/repo[@version]) from
+// the configuration file in dir and updates the lock file
+func (o *Engine) Remove(dir string, names []string) ([]CoordChange, error) {
+	status, err := o.editableConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := configDepsByName(status.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var remove []string
+	for _, name := range names {
+		if fc, err := model.NewCoordFromStr(name); err == nil {
+			name = fc.CanonicalNameNoVersion()
+		}
+		dep, ok := existing[name]
+		if !ok {
+			return nil, fmt.Errorf("`%s` is not a dependency in %s", name, status.ConfigFile)
+		}
+		remove = append(remove, dep)
+	}
+
+	return o.editConfigAndRelock(dir, status, nil, nil, func() error {
+		for _, dep := range remove {
+			if err := utils.RemoveConfigDep(status.ConfigFile, dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// editableConfig returns the lock status of dir making sure there is a
+// configuration file that can be modified
+func (o *Engine) editableConfig(dir string) (*LockStatus, error) {
+	if o.appCtx.Frozen {
+		return nil, fmt.Errorf("dependencies cannot be changed in frozen mode")
+	}
+
+	status, err := o.lockStatus(dir)
+	if err != nil {
+		return nil, err
+	}
+	if status.Config == nil {
+		return nil, fmt.Errorf("no configuration file found in %s", dir)
+	}
+	return status, nil
+}
+
+// editConfigAndRelock runs edit and updates the lock file resolving again the
+// dependencies in resolve and with the dependencies in pinned.  The
+// configuration file is restored if anything fails
+func (o *Engine) editConfigAndRelock(
+	dir string,
+	status *LockStatus,
+	resolve map[string]string,
+	pinned map[string]*model.LockedCoord,
+	edit func() error,
+) ([]CoordChange, error) {
+	original, err := os.ReadFile(status.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	restore := func(err error) error {
+		if e := os.WriteFile(status.ConfigFile, original, 0644); e != nil {
+			Warn.Printf("unable to restore %s: %s", status.ConfigFile, e)
+		}
+		return err
+	}
+
+	if err := edit(); err != nil {
+		return nil, restore(err)
+	}
+
+	var oldDeps []*model.LockedCoord
+	if status.Lock != nil {
+		oldDeps = status.Lock.Deps
+	}
+	changes, err := o.relock(dir, oldDeps, resolve, pinned)
+	if err != nil {
+		return nil, restore(err)
+	}
+	return changes, nil
+}
+
+// configDepsByName returns canonical name => dependency string in cc
+func configDepsByName(cc *model.FuzzyConfigContent) (map[string]string, error) {
+	deps := make(map[string]string)
+	for _, dep := range cc.Deps {
+		fc, err := model.NewCoordFromStr(dep)
+		if err != nil {
+			return nil, err
+		}
+		deps[fc.CanonicalNameNoVersion()] = dep
+	}
+	return deps, nil
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddRemove(t *testing.T) {
+	tmp := t.TempDir()
+	appCtx := model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+		ConfigFileNames:  []string{".bz.hcl", ".bz.json", ".bz"},
+	}
+	engine := NewEngine(appCtx)
+	engine.AddResolver(resolver.NewLocalDevResolver(&appCtx))
+
+	// local dependency
+	dep := "local" + filepath.ToSlash(filepath.Join(tmp, "tool"))
+	writeCacheTestFile(t, filepath.Join(tmp, "tool", ".bz.lock"), `{}`)
+
+	project := filepath.Join(tmp, "project")
+	configFile := filepath.Join(project, ".bz.hcl")
+	writeCacheTestFile(t, configFile, "# no deps yet\ndeps = []\n")
+
+	changes, err := engine.Add(project, []string{dep})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	b, _ := os.ReadFile(configFile)
+	assert.Equal(t, "# no deps yet\ndeps = [\""+dep+"\"]\n", string(b))
+
+	lcc, err := model.LockedConfigContentFromFile(filepath.Join(project, ".bz.lock"))
+	assert.NoError(t, err)
+	assert.Len(t, lcc.Deps, 1)
+
+	_, err = engine.Add(project, []string{"not-a-coord"})
+	assert.Error(t, err)
+
+	changes, err = engine.Remove(project, []string{dep})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	b, _ = os.ReadFile(configFile)
+	assert.Equal(t, "# no deps yet\ndeps = []\n", string(b))
+
+	_, err = engine.Remove(project, []string{dep})
+	assert.ErrorContains(t, err, "is not a dependency")
+}
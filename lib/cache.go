@@ -0,0 +1,497 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+const (
+	lastUsedFileName     = ".last-used"  // touched in the version dir every time the dependency is used
+	lockRegistryFileName = "locks.json"  // lock files used by older versions of bz => last time they were used
+	lockMarkersDirName   = "locks"       // one file per lock file used by bz, modified every time it is used
+	staleTmpAge          = 1 * time.Hour // younger .tmp files may belong to a download in progress
+
+	// EnvSnapshotDirName is the dir in the cache where `:hook` saves the
+	// environment of the projects
+	EnvSnapshotDirName = "env"
+)
+
+// CacheEntry is a version of a dependency in the cache.  e.g.:
+// ~/.bz/cache/deps/github.com/bazurto/python/v3.11.1
+type CacheEntry struct {
+	Server   string    `json:"server"`
+	Owner    string    `json:"owner"`
+	Repo     string    `json:"repo"`
+	Version  string    `json:"version"`  // no v
+	Dir      string    `json:"dir"`      // version dir
+	Size     int64     `json:"size"`     // bytes, including the downloaded asset
+	LastUsed time.Time `json:"lastUsed"` // zero when unknown
+}
+
+// Name returns server/owner/repo
+func (e *CacheEntry) Name() string {
+	return fmt.Sprintf("%s/%s/%s", e.Server, e.Owner, e.Repo)
+}
+
+func (e *CacheEntry) String() string {
+	return fmt.Sprintf("%s@%s", e.Name(), e.Version)
+}
+
+// Matches returns true if coord (server/owner/repo[@version]) refers to this entry.
+// Without version every version matches
+func (e *CacheEntry) Matches(coord string) bool {
+	name, version, _ := strings.Cut(coord, "@")
+	if name != e.Name() {
+		return false
+	}
+	return version == "" || strings.TrimPrefix(version, "v") == e.Version
+}
+
+// CacheProblem is an entry that failed verification
+type CacheProblem struct {
+	Entry *CacheEntry
+	Err   error
+}
+
+func (p CacheProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Entry, p.Err)
+}
+
+// PruneOptions decides which cache entries are removed by Prune
+type PruneOptions struct {
+	Recent    time.Duration // lock files used within Recent keep the dependencies they reference
+	OlderThan time.Duration // when > 0 also remove entries not used within OlderThan, referenced or not
+	DryRun    bool          // only report what would be removed
+	Force     bool          // prune even if no lock file is known or the known ones cannot be read
+}
+
+// cacheDepsDir returns ~/.bz/cache/deps
+func (o *Engine) cacheDepsDir() string {
+	return filepath.Join(o.appCtx.UserCacheDirName, "deps")
+}
+
+// cacheVersionDir returns the directory where lc is downloaded and extracted
+func (o *Engine) cacheVersionDir(lc *model.LockedCoord) string {
+	return filepath.Join(
+		o.cacheDepsDir(),
+		lc.Server,
+		lc.Owner,
+		lc.Repo,
+		fmt.Sprintf("v%s", lc.Version.Canonical()),
+	)
+}
+
+// CacheEntries returns every dependency version in the cache sorted by name and version
+func (o *Engine) CacheEntries() ([]*CacheEntry, error) {
+	// deps/<server>/<owner>/<repo>/v<version>
+	depsDir := o.cacheDepsDir()
+	versionDirs, err := filepath.Glob(filepath.Join(depsDir, "*", "*", "*", "v*"))
+	if err != nil {
+		return nil, fmt.Errorf("CacheEntries(): %w", err)
+	}
+
+	var entries []*CacheEntry
+	for _, dir := range versionDirs {
+		stat, err := os.Stat(dir)
+		if err != nil || !stat.IsDir() {
+			continue
+		}
+		rel, _ := filepath.Rel(depsDir, dir)
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		entry := &CacheEntry{
+			Server:  parts[0],
+			Owner:   parts[1],
+			Repo:    parts[2],
+			Version: strings.TrimPrefix(parts[3], "v"),
+			Dir:     dir,
+		}
+		entry.Size, err = dirSize(dir)
+		if err != nil {
+			return nil, fmt.Errorf("CacheEntries(): %w", err)
+		}
+		if stat, err := os.Stat(filepath.Join(dir, lastUsedFileName)); err == nil {
+			entry.LastUsed = stat.ModTime()
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Name() != entries[j].Name() {
+			return entries[i].Name() < entries[j].Name()
+		}
+		vi := model.NewVersion(entries[i].Version)
+		return vi.Compare(model.NewVersion(entries[j].Version)) < 0
+	})
+	return entries, nil
+}
+
+// RemoveCacheEntries removes the cache entries matching coord (server/owner/repo[@version])
+func (o *Engine) RemoveCacheEntries(coord string) ([]*CacheEntry, error) {
+	entries, err := o.CacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []*CacheEntry
+	for _, entry := range entries {
+		if !entry.Matches(coord) {
+			continue
+		}
+		if err := o.removeCacheEntry(entry); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// ClearCache removes every dependency from the cache
+func (o *Engine) ClearCache() ([]*CacheEntry, error) {
+	entries, err := o.CacheEntries()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(o.cacheDepsDir()); err != nil {
+		return nil, fmt.Errorf("ClearCache(): %w", err)
+	}
+	return entries, nil
+}
+
+// Prune removes the cache entries not referenced by any lock file used
+// recently, stale .tmp downloads and the environment snapshots not used
+// recently.  It returns the removed entries and files.
+//
+// Without the lock files used by bz every entry would be removed, so Prune
+// fails when none is known or they cannot be read unless opts.Force is set
+func (o *Engine) Prune(opts PruneOptions) ([]*CacheEntry, []string, error) {
+	registry, err := o.lockRegistry()
+	if err != nil && !opts.Force {
+		return nil, nil, fmt.Errorf("Prune(): %w.  Use --force to prune anyway", err)
+	}
+	if len(registry) == 0 && !opts.Force {
+		return nil, nil, fmt.Errorf("Prune(): no lock file used by bz is known, every dependency would be removed.  Use --force to prune anyway")
+	}
+
+	entries, err := o.CacheEntries()
+	if err != nil {
+		return nil, nil, err
+	}
+	referenced := o.referencedCacheDirs(registry, time.Now().Add(-opts.Recent))
+	tmpFiles, err := o.staleTmpFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshots, err := o.staleEnvSnapshots(time.Now().Add(-opts.Recent))
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpFiles = append(tmpFiles, snapshots...)
+
+	var removed []*CacheEntry
+	for _, entry := range entries {
+		// entries cached before the last use was recorded are kept while referenced
+		unused := opts.OlderThan > 0 && !entry.LastUsed.IsZero() && entry.LastUsed.Before(time.Now().Add(-opts.OlderThan))
+		if referenced[entry.Dir] && !unused {
+			continue
+		}
+		if !opts.DryRun {
+			if err := o.removeCacheEntry(entry); err != nil {
+				return removed, nil, err
+			}
+		}
+		removed = append(removed, entry)
+	}
+
+	if !opts.DryRun {
+		for _, f := range tmpFiles {
+			// already gone if its entry was removed
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return removed, nil, fmt.Errorf("Prune(): %w", err)
+			}
+		}
+	}
+	return removed, tmpFiles, nil
+}
+
+// VerifyCache checks that every entry was extracted, has a readable lock file
+// and that the downloaded archive matches its recorded digest
+func (o *Engine) VerifyCache() ([]*CacheEntry, []CacheProblem, error) {
+	entries, err := o.CacheEntries()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var problems []CacheProblem
+	for _, entry := range entries {
+		if err := o.verifyCacheEntry(entry); err != nil {
+			problems = append(problems, CacheProblem{Entry: entry, Err: err})
+		}
+	}
+	return entries, problems, nil
+}
+
+func (o *Engine) verifyCacheEntry(entry *CacheEntry) error {
+	extracted := filepath.Join(entry.Dir, "extracted")
+	if !utils.FileExists(extracted) {
+		return fmt.Errorf("not extracted")
+	}
+	if _, err := o.lockedConfigContentFromDir(extracted); err != nil {
+		return err
+	}
+	tmpFiles, _ := filepath.Glob(filepath.Join(entry.Dir, "*.tmp"))
+	if len(tmpFiles) > 0 {
+		return fmt.Errorf("incomplete download %s", filepath.Base(tmpFiles[0]))
+	}
+
+	// the archive has to match the digest recorded when it was downloaded
+	asset, err := resolver.ReadAssetMetadata(entry.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	archive := filepath.Join(entry.Dir, asset.Name)
+	if !utils.FileExists(archive) {
+		return nil
+	}
+	digest, err := utils.FileSha256(archive)
+	if err != nil {
+		return err
+	}
+	if digest != asset.Sha256 {
+		return fmt.Errorf("sha256 mismatch for %s: expected %s, found %s", asset.Name, asset.Sha256, digest)
+	}
+	return nil
+}
+
+// removeCacheEntry removes the version dir and the empty repo and owner dirs
+func (o *Engine) removeCacheEntry(entry *CacheEntry) error {
+	if err := os.RemoveAll(entry.Dir); err != nil {
+		return fmt.Errorf("removeCacheEntry(%s): %w", entry, err)
+	}
+	for dir := filepath.Dir(entry.Dir); dir != o.cacheDepsDir(); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil { // not empty
+			break
+		}
+	}
+	return nil
+}
+
+// staleTmpFiles returns the .tmp files left behind by interrupted downloads
+func (o *Engine) staleTmpFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(o.cacheDepsDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		// do not look inside extracted dependencies
+		if d.IsDir() && d.Name() == "extracted" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".tmp" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if time.Since(info.ModTime()) > staleTmpAge {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("staleTmpFiles(): %w", err)
+	}
+	return files, nil
+}
+
+// staleEnvSnapshots returns the environment snapshots not used after since.
+// They are created again the next time the project is entered
+func (o *Engine) staleEnvSnapshots(since time.Time) ([]string, error) {
+	snapshots, err := filepath.Glob(filepath.Join(o.appCtx.UserCacheDirName, EnvSnapshotDirName, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("staleEnvSnapshots(): %w", err)
+	}
+
+	var files []string
+	for _, f := range snapshots {
+		if stat, err := os.Stat(f); err == nil && stat.ModTime().Before(since) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// referencedCacheDirs returns the version dirs referenced directly or transitively by
+// the lock files in registry used after since
+func (o *Engine) referencedCacheDirs(registry map[string]time.Time, since time.Time) map[string]bool {
+	referenced := make(map[string]bool)
+	visited := make(map[string]bool)
+	var visit func(lockFile string)
+	visit = func(lockFile string) {
+		if visited[lockFile] {
+			return
+		}
+		visited[lockFile] = true
+
+		lcc, err := model.LockedConfigContentFromFile(lockFile)
+		if err != nil {
+			Debug.Printf("referencedCacheDirs(): %s", err)
+			return
+		}
+		// the resolved section lists the whole graph, even if some
+		// intermediate dependency is no longer in the cache
+		for _, n := range lcc.Resolved {
+			if dir := o.cacheVersionDir(&n.LockedCoord); utils.FileExists(dir) {
+				referenced[dir] = true
+			}
+		}
+		for _, dep := range lcc.Deps {
+			dir := o.cacheVersionDir(dep)
+			if utils.FileExists(dir) {
+				referenced[dir] = true
+				visit(filepath.Join(dir, "extracted", o.appCtx.LockFileName))
+			} else if dep.Server == "local" || dep.Server == "local.local" {
+				visit(filepath.Join(dep.Repo, o.appCtx.LockFileName))
+			}
+		}
+	}
+
+	for lockFile, used := range registry {
+		if used.After(since) && utils.FileExists(lockFile) {
+			visit(lockFile)
+		}
+	}
+	return referenced
+}
+
+// lockRegistry returns the lock files used by bz and when they were last used.
+// Every lock file has a marker in ~/.bz/cache/locks so that concurrent bz
+// processes never overwrite each other.  Lock files registered by older
+// versions of bz in ~/.bz/cache/locks.json are included too
+func (o *Engine) lockRegistry() (map[string]time.Time, error) {
+	registry := make(map[string]time.Time)
+	f := filepath.Join(o.appCtx.UserCacheDirName, lockRegistryFileName)
+	b, err := os.ReadFile(f)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("lockRegistry(): %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &registry); err != nil {
+			return nil, fmt.Errorf("lockRegistry(): %s: %w", f, err)
+		}
+	}
+
+	markers, err := filepath.Glob(filepath.Join(o.lockMarkersDir(), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("lockRegistry(): %w", err)
+	}
+	for _, marker := range markers {
+		var lockFile string
+		if err := utils.JsonLoad(marker, &lockFile); err != nil {
+			return nil, fmt.Errorf("lockRegistry(): %s: %w", marker, err)
+		}
+		stat, err := os.Stat(marker)
+		if err != nil {
+			return nil, fmt.Errorf("lockRegistry(): %w", err)
+		}
+		if used := stat.ModTime(); used.After(registry[lockFile]) {
+			registry[lockFile] = used
+		}
+	}
+	return registry, nil
+}
+
+// lockMarkersDir returns ~/.bz/cache/locks
+func (o *Engine) lockMarkersDir() string {
+	return filepath.Join(o.appCtx.UserCacheDirName, lockMarkersDirName)
+}
+
+// registerLockFile records that the lock file in dir was used so that
+// Prune keeps its dependencies.  Errors are only logged
+func (o *Engine) registerLockFile(dir string) {
+	lockFile := filepath.Join(utils.FsAbs(dir), o.appCtx.LockFileName)
+	if !utils.FileExists(lockFile) {
+		return
+	}
+
+	// the marker is named after the lock file and modified every time it is used
+	marker := filepath.Join(o.lockMarkersDir(), fmt.Sprintf("%x.json", sha256.Sum256([]byte(lockFile))))
+	now := time.Now()
+	if err := os.Chtimes(marker, now, now); err == nil {
+		return
+	}
+
+	b, err := json.Marshal(lockFile)
+	if err != nil {
+		Warn.Println(err)
+		return
+	}
+	if err := utils.MkdirIfNotExists(o.lockMarkersDir()); err != nil {
+		Warn.Printf("registerLockFile(): %s", err)
+		return
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", marker, os.Getpid())
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		Warn.Printf("registerLockFile(): %s", err)
+		return
+	}
+	if err := os.Rename(tmp, marker); err != nil {
+		Warn.Printf("registerLockFile(): %s", err)
+	}
+}
+
+// touchCacheEntry updates the last used time of the cache entry extractToDir
+// belongs to.  Dependencies outside of the cache are ignored
+func (o *Engine) touchCacheEntry(extractToDir string) {
+	versionDir := filepath.Dir(utils.FsAbs(extractToDir))
+	if !strings.HasPrefix(versionDir, utils.FsAbs(o.cacheDepsDir())+string(os.PathSeparator)) {
+		return
+	}
+
+	f := filepath.Join(versionDir, lastUsedFileName)
+	now := time.Now()
+	if err := os.Chtimes(f, now, now); err == nil {
+		return
+	}
+	if err := os.WriteFile(f, nil, 0644); err != nil {
+		Debug.Printf("touchCacheEntry(): %s", err)
+	}
+}
+
+// dirSize returns the size of the files in dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCacheTestFile(t *testing.T, f, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(f), 0755))
+	assert.NoError(t, os.WriteFile(f, []byte(content), 0644))
+}
+
+func TestCachePrune(t *testing.T) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+	})
+	deps := filepath.Join(tmp, "cache", "deps", "github.com", "bazurto")
+	writeCacheTestFile(t, filepath.Join(deps, "python", "v2.7.18", "extracted", ".bz.lock"), `{}`)
+	writeCacheTestFile(t, filepath.Join(deps, "python", "v3.11.1", "extracted", ".bz.lock"),
+		`{"deps":[{"server":"github.com","owner":"bazurto","repo":"openssl","version":"3.0.0"}]}`)
+	writeCacheTestFile(t, filepath.Join(deps, "openssl", "v3.0.0", "extracted", ".bz.lock"), `{}`)
+	writeCacheTestFile(t, filepath.Join(deps, "groovy", "v4.0.0", "groovy.tgz.tmp"), ``)
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(deps, "groovy", "v4.0.0", "groovy.tgz.tmp"), old, old))
+	envDir := filepath.Join(tmp, "cache", EnvSnapshotDirName)
+	writeCacheTestFile(t, filepath.Join(envDir, "old.json"), `{}`)
+	assert.NoError(t, os.Chtimes(filepath.Join(envDir, "old.json"), old, old))
+	writeCacheTestFile(t, filepath.Join(envDir, "new.json"), `{}`)
+
+	// no lock file known, everything would be removed
+	_, _, err := engine.Prune(PruneOptions{Recent: 24 * time.Hour})
+	assert.Error(t, err)
+	removed, _, err := engine.Prune(PruneOptions{Recent: 24 * time.Hour, DryRun: true, Force: true})
+	assert.NoError(t, err)
+	assert.Len(t, removed, 4)
+
+	// project using python 3
+	project := filepath.Join(tmp, "project")
+	writeCacheTestFile(t, filepath.Join(project, ".bz.lock"),
+		`{"deps":[{"server":"github.com","owner":"bazurto","repo":"python","version":"3.11.1"}]}`)
+	engine.registerLockFile(project)
+
+	entries, err := engine.CacheEntries()
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.String())
+	}
+	assert.Equal(t, []string{
+		"github.com/bazurto/groovy@4.0.0",
+		"github.com/bazurto/openssl@3.0.0",
+		"github.com/bazurto/python@2.7.18",
+		"github.com/bazurto/python@3.11.1",
+	}, names)
+
+	removed, tmpFiles, err := engine.Prune(PruneOptions{Recent: 24 * time.Hour})
+	assert.NoError(t, err)
+	names = nil
+	for _, e := range removed {
+		names = append(names, e.String())
+	}
+	assert.Equal(t, []string{"github.com/bazurto/groovy@4.0.0", "github.com/bazurto/python@2.7.18"}, names)
+	assert.Equal(t, []string{
+		filepath.Join(deps, "groovy", "v4.0.0", "groovy.tgz.tmp"),
+		filepath.Join(envDir, "old.json"),
+	}, tmpFiles)
+	assert.NoDirExists(t, filepath.Join(deps, "groovy"))
+	assert.NoFileExists(t, filepath.Join(envDir, "old.json"))
+	assert.FileExists(t, filepath.Join(envDir, "new.json"))
+	assert.DirExists(t, filepath.Join(deps, "openssl", "v3.0.0"))
+
+	// referenced entries without last use are kept by --older-than, the others
+	// are removed when not used recently
+	writeCacheTestFile(t, filepath.Join(deps, "openssl", "v3.0.0", lastUsedFileName), ``)
+	assert.NoError(t, os.Chtimes(filepath.Join(deps, "openssl", "v3.0.0", lastUsedFileName), old, old))
+	removed, _, err = engine.Prune(PruneOptions{Recent: 24 * time.Hour, OlderThan: 24 * time.Hour})
+	assert.NoError(t, err)
+	names = nil
+	for _, e := range removed {
+		names = append(names, e.String())
+	}
+	assert.Equal(t, []string{"github.com/bazurto/openssl@3.0.0"}, names)
+	assert.DirExists(t, filepath.Join(deps, "python", "v3.11.1"))
+
+	removed, err = engine.RemoveCacheEntries("github.com/bazurto/python")
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.NoDirExists(t, filepath.Join(deps, "python"))
+}
+
+func TestLockRegistry(t *testing.T) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+	})
+	registry, err := engine.lockRegistry()
+	assert.NoError(t, err)
+	assert.Empty(t, registry)
+
+	// registered by an older bz
+	old := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	legacy := filepath.Join(tmp, "legacy", ".bz.lock")
+	writeCacheTestFile(t, filepath.Join(tmp, "cache", "locks.json"), fmt.Sprintf(`{%q: %q}`, legacy, old.Format(time.RFC3339)))
+
+	// every project has its own marker, concurrent registrations are not lost
+	var projects []string
+	for i := 0; i < 10; i++ {
+		project := filepath.Join(tmp, fmt.Sprintf("project%d", i))
+		writeCacheTestFile(t, filepath.Join(project, ".bz.lock"), `{}`)
+		projects = append(projects, project)
+	}
+	done := make(chan bool)
+	for _, project := range projects {
+		go func(project string) {
+			engine.registerLockFile(project)
+			done <- true
+		}(project)
+	}
+	for range projects {
+		<-done
+	}
+
+	registry, err = engine.lockRegistry()
+	assert.NoError(t, err)
+	assert.Len(t, registry, len(projects)+1)
+	assert.True(t, registry[legacy].Equal(old))
+	for _, project := range projects {
+		assert.WithinDuration(t, time.Now(), registry[filepath.Join(project, ".bz.lock")], time.Minute)
+	}
+
+	// unreadable registry
+	markers, _ := filepath.Glob(filepath.Join(tmp, "cache", "locks", "*.json"))
+	writeCacheTestFile(t, markers[0], `{`)
+	_, err = engine.lockRegistry()
+	assert.Error(t, err)
+	_, _, err = engine.Prune(PruneOptions{DryRun: true})
+	assert.Error(t, err)
+	_, _, err = engine.Prune(PruneOptions{DryRun: true, Force: true})
+	assert.NoError(t, err)
+}
+
+func TestCacheVerify(t *testing.T) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+	})
+	deps := filepath.Join(tmp, "cache", "deps", "github.com", "bazurto")
+	for _, repo := range []string{"python", "groovy"} {
+		writeCacheTestFile(t, filepath.Join(deps, repo, "v1.0.0", "extracted", ".bz.lock"), `{}`)
+		writeCacheTestFile(t, filepath.Join(deps, repo, "v1.0.0", repo+"-v1.0.0.tgz"), "archive")
+		writeCacheTestFile(t, filepath.Join(deps, repo, "v1.0.0", ".asset.json"),
+			`{"name":"`+repo+`-v1.0.0.tgz","sha256":"`+fmt.Sprintf("%x", sha256.Sum256([]byte("archive")))+`"}`)
+	}
+	writeCacheTestFile(t, filepath.Join(deps, "groovy", "v1.0.0", "groovy-v1.0.0.tgz"), "changed")
+
+	entries, problems, err := engine.VerifyCache()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "github.com/bazurto/groovy@1.0.0", problems[0].Entry.String())
+	assert.ErrorContains(t, problems[0].Err, "sha256 mismatch")
+}
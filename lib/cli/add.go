@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"github.com/bazurto/bz/lib/model"
+)
+
+var addCmd = &Command{
+	Name:  "add",
+	Usage: "<server/owner/repo[@version]>...",
+	Short: "Add dependencies to .bz.hcl and update .bz.lock.",
+}
+
+func init() {
+	addCmd.Run = runAdd
+	register(addCmd)
+}
+
+func runAdd(app *App, args []string) error {
+	fs := app.FlagSet(addCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("missing dependency")
+	}
+	for _, dep := range fs.Args() {
+		if _, err := model.NewCoordFromStr(dep); err != nil {
+			return usageErrorf("%s", err)
+		}
+	}
+
+	changes, err := app.Engine().Add(app.ProjectDir(), fs.Args())
+	if err != nil {
+		return err
+	}
+	printChanges(app, changes)
+	return nil
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+)
+
+var allowCmd = &Command{
+	Name:  "allow",
+	Usage: "",
+	Short: "Allow the shell hook to activate the project with its current configuration and lock file.",
+}
+
+func init() {
+	allowCmd.Run = runAllow
+	register(allowCmd)
+}
+
+func runAllow(app *App, args []string) error {
+	fs := app.FlagSet(allowCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("unexpected arguments")
+	}
+	if app.Project().File == "" {
+		return fmt.Errorf("no project configuration found in %s", app.ProjectDir())
+	}
+
+	if err := hookAllow(app, hookProjectKey(app)); err != nil {
+		return err
+	}
+	fmt.Fprintf(app.Stdout, "allowed %s\n", app.ProjectDir())
+	return nil
+}
@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bazurto/bz/lib"
+)
+
+var cacheCmd = &Command{
+	Name:  "cache",
+	Usage: "ls|prune|rm|verify|clear [flags] [args...]",
+	Short: "Manage the dependency cache (~/.bz/cache/deps).",
+}
+
+// cache sub commands.  They are invoked as `bz :cache <name>`
+var cacheSubCmds = map[string]*Command{
+	"ls": {
+		Name:  "cache ls",
+		Usage: "[--json]",
+		Short: "List cached dependencies with their size and last time they were used.",
+	},
+	"prune": {
+		Name:  "cache prune",
+		Usage: "[--recent days] [--older-than days] [--dry-run] [--force]",
+		Short: "Remove dependencies not referenced by lock files used recently and incomplete downloads.",
+	},
+	"rm": {
+		Name:  "cache rm",
+		Usage: "<server/owner/repo[@version]>...",
+		Short: "Remove dependencies from the cache. Without version every version is removed.",
+	},
+	"verify": {
+		Name:  "cache verify",
+		Usage: "",
+		Short: "Check that cached dependencies are complete.",
+	},
+	"clear": {
+		Name:  "cache clear",
+		Usage: "",
+		Short: "Remove every dependency from the cache.",
+	},
+}
+
+func init() {
+	cacheCmd.Run = runCache
+	cacheSubCmds["ls"].Run = runCacheLs
+	cacheSubCmds["prune"].Run = runCachePrune
+	cacheSubCmds["rm"].Run = runCacheRm
+	cacheSubCmds["verify"].Run = runCacheVerify
+	cacheSubCmds["clear"].Run = runCacheClear
+	register(cacheCmd)
+}
+
+func runCache(app *App, args []string) error {
+	fs := app.FlagSet(cacheCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("missing sub command")
+	}
+	sub, ok := cacheSubCmds[fs.Arg(0)]
+	if !ok {
+		return usageErrorf("unknown sub command `%s`", fs.Arg(0))
+	}
+	return sub.Run(app, fs.Args()[1:])
+}
+
+func runCacheLs(app *App, args []string) error {
+	cmd := cacheSubCmds["ls"]
+	fs := app.FlagSet(cmd)
+	asJson := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	entries, err := app.Engine().CacheEntries()
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		if entries == nil {
+			entries = []*lib.CacheEntry{}
+		}
+		enc := json.NewEncoder(app.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	var total int64
+	tw := tabwriter.NewWriter(app.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "DEPENDENCY\tVERSION\tSIZE\tLAST USED\n")
+	for _, e := range entries {
+		total += e.Size
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Name(), e.Version, humanSize(e.Size), lastUsed(e.LastUsed))
+	}
+	fmt.Fprintf(tw, "\t\t%s\t%d versions\n", humanSize(total), len(entries))
+	return tw.Flush()
+}
+
+func runCachePrune(app *App, args []string) error {
+	cmd := cacheSubCmds["prune"]
+	fs := app.FlagSet(cmd)
+	recent := fs.Int("recent", 30, "keep dependencies referenced by lock files used in the last `days`")
+	olderThan := fs.Int("older-than", 0, "also remove dependencies not used in the last `days`, even if referenced (kept when the last use is unknown)")
+	dryRun := fs.Bool("dry-run", false, "print what would be removed without removing it")
+	force := fs.Bool("force", false, "prune even if bz does not know any lock file, e.g. after deleting ~/.bz/cache/locks")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+	if *recent < 0 || *olderThan < 0 {
+		return usageErrorf("days must be positive")
+	}
+
+	day := 24 * time.Hour
+	removed, tmpFiles, err := app.Engine().Prune(lib.PruneOptions{
+		Recent:    time.Duration(*recent) * day,
+		OlderThan: time.Duration(*olderThan) * day,
+		DryRun:    *dryRun,
+		Force:     *force,
+	})
+
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+		fmt.Fprintf(app.Stdout, "%s %s (%s)\n", verb, e, humanSize(e.Size))
+	}
+	for _, f := range tmpFiles {
+		fmt.Fprintf(app.Stdout, "%s %s\n", verb, f)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(app.Stdout, "%s %d versions, %s\n", verb, len(removed), humanSize(freed))
+	return nil
+}
+
+func runCacheRm(app *App, args []string) error {
+	cmd := cacheSubCmds["rm"]
+	fs := app.FlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("missing dependency")
+	}
+
+	engine := app.Engine()
+	for _, coord := range fs.Args() {
+		removed, err := engine.RemoveCacheEntries(coord)
+		for _, e := range removed {
+			fmt.Fprintf(app.Stdout, "removed %s (%s)\n", e, humanSize(e.Size))
+		}
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return fmt.Errorf("`%s` is not in the cache", coord)
+		}
+	}
+	return nil
+}
+
+func runCacheVerify(app *App, args []string) error {
+	cmd := cacheSubCmds["verify"]
+	fs := app.FlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	entries, problems, err := app.Engine().VerifyCache()
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintf(app.Stdout, "%s\n", p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d of %d cached versions are broken; remove them with `bz %scache rm`", len(problems), len(entries), CommandPrefix)
+	}
+	fmt.Fprintf(app.Stdout, "%d cached versions ok\n", len(entries))
+	return nil
+}
+
+func runCacheClear(app *App, args []string) error {
+	cmd := cacheSubCmds["clear"]
+	fs := app.FlagSet(cmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	removed, err := app.Engine().ClearCache()
+	if err != nil {
+		return err
+	}
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+	}
+	fmt.Fprintf(app.Stdout, "removed %d versions, %s\n", len(removed), humanSize(freed))
+	return nil
+}
+
+// humanSize formats bytes as B, KiB, MiB ...
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// lastUsed formats t relative to now
+func lastUsed(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+}
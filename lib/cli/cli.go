@@ -0,0 +1,376 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+)
+
+// Exit codes.  When a command is passed through, bz exits with
+// the exit code of the executed command instead.
+const (
+	ExitOK    = 0 // success
+	ExitError = 1 // command failed
+	ExitUsage = 2 // invalid flags, arguments or unknown command
+)
+
+// CommandPrefix distinguishes bz commands from commands passed through
+// to the resolved environment.  e.g.:  `bz :lock` vs `bz python`
+const CommandPrefix = ":"
+
+var (
+	commands = make(map[string]*Command)
+)
+
+// Command is a bz command invoked as `bz :name [args...]`
+type Command struct {
+	Name  string // name without prefix. e.g.: lock
+	Usage string // arguments synopsis. e.g.: [flags] [dep...]
+	Short string // one line description shown in `bz :help`
+	Run   func(app *App, args []string) error
+}
+
+// register adds a command to the list of available commands.  It is
+// meant to be called from init()
+func register(cmd *Command) {
+	if _, ok := commands[cmd.Name]; ok {
+		panic(fmt.Sprintf("command %s registered twice", cmd.Name))
+	}
+	commands[cmd.Name] = cmd
+}
+
+// UsageError is returned by commands invoked with invalid arguments
+type UsageError struct {
+	Msg string
+}
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &UsageError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// ExitCodeError is returned by commands that need to exit with a specific code
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// Options global flags accepted before the command
+type Options struct {
+	Dir        string // directory where to start looking for the project
+	Config     string // user config file
+	Offline    bool   // do not reach the network
+	Frozen     bool   // fail when the lock file is out of date
+	Prerelease bool   // allow pre-releases for every dependency
+	Verbose    bool   // debug output
+}
+
+// App holds everything a command needs to run
+type App struct {
+	Options Options
+	AppCtx  *model.AppContext
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Stdin   io.Reader
+
+	engine  *lib.Engine
+	project *lib.PathFound
+}
+
+func NewApp(stdout, stderr io.Writer, stdin io.Reader) *App {
+	return &App{Stdout: stdout, Stderr: stderr, Stdin: stdin}
+}
+
+// Run runs bz with os.Args[1:] and returns the exit code
+func Run(args []string) int {
+	return NewApp(os.Stdout, os.Stderr, os.Stdin).Run(args)
+}
+
+// Run parses the global flags and dispatches to a bz command or
+// executes the given command within the resolved environment
+func (a *App) Run(args []string) int {
+	fs := flag.NewFlagSet("bz", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	globalFlags(fs, &a.Options)
+	fs.Usage = func() { a.printUsage(a.Stderr) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	rest := fs.Args()
+	// `bz -- :cmd` forces pass through
+	passThrough := stoppedAtTerminator(fs, args[:len(args)-len(rest)])
+
+	if err := a.init(); err != nil {
+		fmt.Fprintf(a.Stderr, "%s\n", err)
+		return ExitError
+	}
+
+	if !passThrough && len(rest) > 0 && strings.HasPrefix(rest[0], CommandPrefix) {
+		name := strings.TrimPrefix(rest[0], CommandPrefix)
+		cmd, ok := commands[name]
+		if !ok {
+			fmt.Fprintf(a.Stderr, "bz: unknown command `%s`. Run `bz %shelp` for usage\n", rest[0], CommandPrefix)
+			return ExitUsage
+		}
+		return a.exitCode(cmd, cmd.Run(a, rest[1:]))
+	}
+
+	return a.execute(rest)
+}
+
+// stoppedAtTerminator returns true if fs stopped parsing the flags in parsed,
+// the arguments it consumed, at a `--` terminator.  `--` can also be the
+// value of a flag: `bz --dir -- :x`
+func stoppedAtTerminator(fs *flag.FlagSet, parsed []string) bool {
+	for i := 0; i < len(parsed); i++ {
+		arg := parsed[i]
+		if arg == "--" {
+			return true
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
+		}
+		i++ // the next argument is the value of the flag
+	}
+	return false
+}
+
+func globalFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.Dir, "dir", "", "look for the project starting at `dir` instead of the current directory")
+	fs.StringVar(&opts.Config, "config", "", "user config `file` (default ~/.bz/config)")
+	fs.BoolVar(&opts.Offline, "offline", envBool("BZ_OFFLINE"), "never reach the network, use lock files and cache only (env BZ_OFFLINE)")
+	fs.BoolVar(&opts.Frozen, "frozen", envBool("BZ_FROZEN"), "fail instead of updating an out of date .bz.lock (env BZ_FROZEN)")
+	fs.BoolVar(&opts.Prerelease, "prerelease", envBool("BZ_PRERELEASE"), "allow pre-releases when resolving every dependency (env BZ_PRERELEASE)")
+	fs.BoolVar(&opts.Verbose, "verbose", false, "print debug information")
+}
+
+func (a *App) init() error {
+	if a.Options.Verbose {
+		lib.EnableDebug()
+	}
+
+	appCtx, err := model.NewAppContext(a.Options.Config)
+	if err != nil {
+		return err
+	}
+	appCtx.Offline = a.Options.Offline
+	appCtx.Frozen = a.Options.Frozen
+	appCtx.Prerelease = a.Options.Prerelease
+	a.AppCtx = appCtx
+	return nil
+}
+
+func (a *App) exitCode(cmd *Command, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var usageErr *UsageError
+	var exitErr *ExitCodeError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(a.Stderr, "bz %s%s: %s\n", CommandPrefix, cmd.Name, usageErr)
+		fmt.Fprintf(a.Stderr, "usage: bz %s%s %s\n", CommandPrefix, cmd.Name, cmd.Usage)
+		return ExitUsage
+	case errors.As(err, &exitErr):
+		if exitErr.Err != nil {
+			fmt.Fprintf(a.Stderr, "bz %s%s: %s\n", CommandPrefix, cmd.Name, exitErr.Err)
+		}
+		return exitErr.Code
+	}
+
+	fmt.Fprintf(a.Stderr, "bz %s%s: %s\n", CommandPrefix, cmd.Name, err)
+	return ExitError
+}
+
+// execute runs args within the resolved environment.  This is the
+// default behavior: `bz python --version`
+func (a *App) execute(args []string) int {
+	engine := a.Engine()
+	rdep, err := engine.ContextFromConfigDir(a.ProjectDir()) // does resolving and downloading
+	if err != nil {
+		fmt.Fprintf(a.Stderr, "%s\n", err)
+		return ExitError
+	}
+
+	// rctx has env vars, aliases and all resolved information
+	return engine.Execute(rdep, args)
+}
+
+// FlagSet returns a flag set for cmd that prints the command usage
+// to stderr
+func (a *App) FlagSet(cmd *Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "usage: bz %s%s %s\n\n%s\n", CommandPrefix, cmd.Name, cmd.Usage, cmd.Short)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(w, "\nflags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args with fs.  The flag package already reports
+// invalid flags, so they are returned as a silent usage exit code
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return &ExitCodeError{Code: ExitUsage}
+}
+
+// Engine returns the engine with all the resolvers registered
+func (a *App) Engine() *lib.Engine {
+	if a.engine != nil {
+		return a.engine
+	}
+
+	// Resolvers
+	ghr := resolver.NewGithubResolver(a.AppCtx)
+	glr := resolver.NewGitLabResolver(a.AppCtx)
+	gtr := resolver.NewGiteaResolver(a.AppCtx)
+	httpr := resolver.NewHTTPResolver(a.AppCtx)
+	local := resolver.NewLocalDevResolver(a.AppCtx)
+	a.engine = lib.NewEngine(*a.AppCtx)
+	a.engine.AddResolver(ghr)
+	a.engine.AddResolver(glr)
+	a.engine.AddResolver(gtr)
+	a.engine.AddResolver(httpr)
+	a.engine.AddResolver(local)
+	return a.engine
+}
+
+// Project returns the location of the project configuration.  File is empty when
+// no configuration file was found.
+func (a *App) Project() *lib.PathFound {
+	if a.project != nil {
+		return a.project
+	}
+
+	var startDir *string
+	if a.Options.Dir != "" {
+		startDir = &a.Options.Dir
+	}
+
+	lib.Debug.Printf("Look for project files: %s", a.AppCtx.ConfigFileNames)
+	// current project
+	projectLocation, _ := lib.FindFileUpwards(a.AppCtx.ConfigFileNames, startDir)
+	if projectLocation == nil {
+		dir := a.Options.Dir
+		if dir == "" {
+			dir, _ = os.Getwd()
+		}
+		projectLocation = &lib.PathFound{
+			File: "",
+			Root: dir,
+		}
+		lib.Debug.Printf("project configuration Not Found.  setting to: %s", projectLocation)
+	} else {
+		lib.Debug.Printf("found project location: %s", projectLocation)
+	}
+	a.project = projectLocation
+	return a.project
+}
+
+// ProjectDir returns the project root directory
+func (a *App) ProjectDir() string {
+	return a.Project().Root
+}
+
+func (a *App) printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: bz [flags] <command> [args...]\n")
+	fmt.Fprintf(w, "       bz [flags] %s<bz-command> [args...]\n", CommandPrefix)
+	fmt.Fprintf(w, "       bz [flags] -- <command> [args...]\n\n")
+	fmt.Fprintf(w, "Executes <command> within the environment resolved from .bz.hcl.\n")
+	fmt.Fprintf(w, "Arguments starting with `%s` are bz commands; use `--` to execute a command\n", CommandPrefix)
+	fmt.Fprintf(w, "whose name starts with `%s`.\n\n", CommandPrefix)
+
+	fmt.Fprintf(w, "bz commands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s%s\t%s\n", CommandPrefix, name, commands[name].Short)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nflags:\n")
+	fs := flag.NewFlagSet("bz", flag.ContinueOnError)
+	fs.SetOutput(w)
+	globalFlags(fs, &Options{})
+	fs.PrintDefaults()
+
+	fmt.Fprintf(w, "\nexit codes:\n")
+	fmt.Fprintf(w, "  %d  success\n", ExitOK)
+	fmt.Fprintf(w, "  %d  error\n", ExitError)
+	fmt.Fprintf(w, "  %d  invalid usage\n", ExitUsage)
+	fmt.Fprintf(w, "  executed commands exit with their own exit code\n")
+}
+
+// stringsFlag is a flag that can be repeated.  e.g.: --dep a --dep b
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// envBool returns true when the env var is set to anything other
+// than empty, 0 or false
+func envBool(name string) bool {
+	v := os.Getenv(name)
+	return v != "" && v != "0" && !strings.EqualFold(v, "false")
+}
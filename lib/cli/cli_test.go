@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runApp(args ...string) (int, string, string) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	code := NewApp(stdout, stderr, bytes.NewBuffer(nil)).Run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunCommand(t *testing.T) {
+	t.Setenv("BZ_INFO", "revision:1")
+	code, stdout, _ := runApp("--offline", ":version")
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, "bz revision:1\n", stdout)
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	code, _, stderr := runApp(":does-not-exist")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, "unknown command")
+}
+
+func TestRunInvalidFlags(t *testing.T) {
+	code, _, _ := runApp("--does-not-exist")
+	assert.Equal(t, ExitUsage, code)
+
+	code, _, _ = runApp(":version", "--does-not-exist")
+	assert.Equal(t, ExitUsage, code)
+}
+
+func TestRunHelp(t *testing.T) {
+	code, stdout, _ := runApp(":help")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, ":version")
+
+	code, _, _ = runApp("--help")
+	assert.Equal(t, ExitOK, code)
+}
+
+func TestStoppedAtTerminator(t *testing.T) {
+	d := map[string]bool{
+		"-- :x":                   true,
+		"--offline -- :x":         true,
+		"--dir -- :x":             false, // -- is the dir
+		"--dir=x -- :x":           true,
+		"--dir -- -- :x":          true,
+		"--offline :x":            false,
+		"--config c --dir d :x":   false,
+		"--config c --dir d -- x": true,
+	}
+	for args, expected := range d {
+		fs := flag.NewFlagSet("bz", flag.ContinueOnError)
+		globalFlags(fs, &Options{})
+		argv := strings.Fields(args)
+		assert.NoError(t, fs.Parse(argv))
+		assert.Equal(t, expected, stoppedAtTerminator(fs, argv[:len(argv)-fs.NArg()]), args)
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	env := map[string]string{"A": "it's", "PATH": "/a:/b"}
+	expected := map[string]string{
+		"sh":         "export A='it'\\''s'\nexport PATH='/a:/b'\n",
+		"fish":       "set -gx A 'it\\'s'\nset -gx PATH '/a' '/b'\n",
+		"powershell": "$Env:A = 'it''s'\n$Env:PATH = '/a:/b'\n",
+		"dotenv":     "A=\"it's\"\nPATH=\"/a:/b\"\n",
+		"direnv":     "watch_file '.bz.hcl'\nexport A='it'\\''s'\nexport PATH='/a:/b'\n",
+	}
+	for format, out := range expected {
+		b := bytes.NewBuffer(nil)
+		assert.Nil(t, writeEnv(b, format, env, []string{".bz.hcl"}))
+		assert.Equal(t, out, b.String(), format)
+	}
+}
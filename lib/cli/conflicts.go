@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bazurto/bz/lib"
+)
+
+var conflictsCmd = &Command{
+	Name:  "conflicts",
+	Usage: "[--json]",
+	Short: "List dependencies required at more than one version, overridden or replaced, and how they were settled.",
+}
+
+func init() {
+	conflictsCmd.Run = runConflicts
+	register(conflictsCmd)
+}
+
+func runConflicts(app *App, args []string) error {
+	fs := app.FlagSet(conflictsCmd)
+	asJson := fs.Bool("json", false, "print JSON")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	conflicts, err := app.Engine().Conflicts(app.ProjectDir())
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		if conflicts == nil {
+			conflicts = []*lib.DependencyConflict{}
+		}
+		enc := json.NewEncoder(app.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(conflicts)
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Fprintf(app.Stdout, "no conflicts\n")
+		return nil
+	}
+	for _, c := range conflicts {
+		if c.Replacement != "" {
+			fmt.Fprintf(app.Stdout, "%s => %s (%s)\n", c.Name, c.Replacement, c.Reason)
+		} else {
+			fmt.Fprintf(app.Stdout, "%s@%s (%s)\n", c.Name, c.Selected, c.Reason)
+		}
+		for _, r := range c.Requested {
+			fmt.Fprintf(app.Stdout, "  %s required by %s\n", r.Version, r.By)
+		}
+		if c.Warning != "" {
+			fmt.Fprintf(app.Stdout, "  warning: %s\n", c.Warning)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var envCmd = &Command{
+	Name:  "env",
+	Usage: "[--format=sh|fish|powershell|dotenv|json|direnv]",
+	Short: "Print the resolved environment, e.g.: eval \"$(bz :env)\".",
+}
+
+func init() {
+	envCmd.Run = runEnv
+	register(envCmd)
+}
+
+func runEnv(app *App, args []string) error {
+	fs := app.FlagSet(envCmd)
+	format := fs.String("format", "sh", fmt.Sprintf("output format: %s", strings.Join(envFormatNames(), ", ")))
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	engine := app.Engine()
+	rdep, err := engine.ContextFromConfigDir(app.ProjectDir())
+	if err != nil {
+		return err
+	}
+
+	var watch []string
+	if app.Project().File != "" {
+		watch = append(watch, app.Project().File, filepath.Join(app.ProjectDir(), app.AppCtx.LockFileName))
+	}
+	return writeEnv(app.Stdout, *format, engine.Environment(rdep), watch)
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envFormat writes environment variables in a shell or file syntax
+type envFormat struct {
+	set   func(w io.Writer, k, v string)
+	unset func(w io.Writer, k string) // nil if the format cannot unset variables
+}
+
+var envFormats = map[string]envFormat{
+	"sh": {
+		set:   func(w io.Writer, k, v string) { fmt.Fprintf(w, "export %s=%s\n", k, shQuote(v)) },
+		unset: func(w io.Writer, k string) { fmt.Fprintf(w, "unset %s\n", k) },
+	},
+	"fish": {
+		set: func(w io.Writer, k, v string) {
+			if strings.HasSuffix(k, "PATH") {
+				// fish path variables are lists
+				var items []string
+				for _, p := range strings.Split(v, string([]rune{os.PathListSeparator})) {
+					items = append(items, fishQuote(p))
+				}
+				fmt.Fprintf(w, "set -gx %s %s\n", k, strings.Join(items, " "))
+				return
+			}
+			fmt.Fprintf(w, "set -gx %s %s\n", k, fishQuote(v))
+		},
+		unset: func(w io.Writer, k string) { fmt.Fprintf(w, "set -e %s\n", k) },
+	},
+	"powershell": {
+		set: func(w io.Writer, k, v string) {
+			fmt.Fprintf(w, "$Env:%s = '%s'\n", k, strings.ReplaceAll(v, "'", "''"))
+		},
+		unset: func(w io.Writer, k string) {
+			fmt.Fprintf(w, "Remove-Item -ErrorAction SilentlyContinue Env:%s\n", k)
+		},
+	},
+	"dotenv": {
+		set: func(w io.Writer, k, v string) {
+			r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`)
+			fmt.Fprintf(w, "%s=\"%s\"\n", k, r.Replace(v))
+		},
+	},
+}
+
+// envFormatNames returns the formats accepted by writeEnv
+func envFormatNames() []string {
+	names := []string{"json", "direnv"}
+	for name := range envFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeEnv writes env to w in the given format.  watch is the list of
+// files direnv should watch for changes
+func writeEnv(w io.Writer, format string, env map[string]string, watch []string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(env)
+	case "direnv":
+		// .envrc: eval "$(bz :env --format=direnv)"
+		for _, f := range watch {
+			fmt.Fprintf(w, "watch_file %s\n", shQuote(f))
+		}
+		format = "sh"
+	}
+
+	f, ok := envFormats[format]
+	if !ok {
+		return usageErrorf("unknown format `%s`. Valid formats: %s", format, strings.Join(envFormatNames(), ", "))
+	}
+	for _, k := range sortedKeys(env) {
+		f.set(w, k, env[k])
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shQuote quotes s for POSIX shells
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for the fish shell
+func fishQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(s) + "'"
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+	"os"
+)
+
+var (
+	helpCmd = &Command{
+		Name:  "help",
+		Usage: "[command]",
+		Short: "Show usage for bz or for a bz command.",
+	}
+	versionCmd = &Command{
+		Name:  "version",
+		Usage: "",
+		Short: "Print bz build information.",
+	}
+)
+
+func init() {
+	helpCmd.Run = runHelp
+	versionCmd.Run = runVersion
+	register(helpCmd)
+	register(versionCmd)
+}
+
+func runHelp(app *App, args []string) error {
+	fs := app.FlagSet(helpCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageErrorf("too many arguments")
+	}
+
+	if fs.NArg() == 0 {
+		app.printUsage(app.Stdout)
+		return nil
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		return usageErrorf("unknown command `%s`", fs.Arg(0))
+	}
+	return cmd.Run(app, []string{"-h"})
+}
+
+func runVersion(app *App, args []string) error {
+	fs := app.FlagSet(versionCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	info := os.Getenv("BZ_INFO")
+	if info == "" {
+		info = "unknown"
+	}
+	fmt.Fprintf(app.Stdout, "bz %s\n", info)
+	return nil
+}
@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+const (
+	hookKeyVar  = "BZ_HOOK_KEY"  // snapshot key of the active project, !key when it is not allowed
+	hookPrevVar = "BZ_HOOK_PREV" // values to restore when leaving the project
+)
+
+// hookAllowDirName is the dir of ~/.bz with a file for every snapshot key
+// allowed with `:allow`
+const hookAllowDirName = "allow"
+
+var hookCmd = &Command{
+	Name:  "hook",
+	Usage: "[--export] bash|zsh|fish",
+	Short: "Print a shell hook that activates the project environment on `cd`, e.g.: eval \"$(bz :hook bash)\".",
+}
+
+// hookScripts are installed in the shell rc file.  %[1]s is the bz executable
+var hookScripts = map[string]string{
+	"bash": `_bz_hook() {
+  local previous_exit_status=$?
+  eval "$(%[1]s :hook --export bash)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_bz_hook;"* ]]; then
+  PROMPT_COMMAND="_bz_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	"zsh": `_bz_hook() {
+  eval "$(%[1]s :hook --export zsh)"
+}
+typeset -ag precmd_functions
+if (( ! ${precmd_functions[(I)_bz_hook]} )); then
+  precmd_functions=(_bz_hook $precmd_functions)
+fi
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_bz_hook]} )); then
+  chpwd_functions=(_bz_hook $chpwd_functions)
+fi
+`,
+	"fish": `function __bz_hook --on-event fish_prompt
+  %[1]s :hook --export fish | source
+end
+function __bz_hook_cd --on-variable PWD
+  %[1]s :hook --export fish | source
+end
+`,
+}
+
+// hookFormats maps the shell to the env format used to export variables
+var hookFormats = map[string]string{
+	"bash": "sh",
+	"zsh":  "sh",
+	"fish": "fish",
+}
+
+func init() {
+	hookCmd.Run = runHook
+	register(hookCmd)
+}
+
+func runHook(app *App, args []string) error {
+	fs := app.FlagSet(hookCmd)
+	export := fs.Bool("export", false, "print the commands that activate or deactivate the environment of the current directory (used by the hook)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("shell is required")
+	}
+	shell := fs.Arg(0)
+	script, ok := hookScripts[shell]
+	if !ok {
+		return usageErrorf("unsupported shell `%s`", shell)
+	}
+
+	if *export {
+		return hookExport(app, envFormats[hookFormats[shell]])
+	}
+
+	bz, err := os.Executable()
+	if err != nil {
+		bz = "bz"
+	}
+	fmt.Fprintf(app.Stdout, script, shQuote(bz))
+	return nil
+}
+
+// hookExport restores the variables changed when entering the previous
+// project and applies the environment of the project found in the current
+// directory.  Nothing is printed while staying in the same project.
+// Resolving a project downloads its dependencies and runs their install
+// scripts, so projects are only activated once their configuration and lock
+// file are allowed with `:allow`
+func hookExport(app *App, f envFormat) error {
+	project := app.Project()
+	key := ""
+	if project.File != "" {
+		key = hookProjectKey(app)
+	}
+	if key == os.Getenv(hookKeyVar) {
+		return nil // nothing changed
+	}
+	allowed := key == "" || hookAllowed(app, key)
+	if !allowed && "!"+key == os.Getenv(hookKeyVar) {
+		return nil // already reported
+	}
+
+	base := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			base[k] = v
+		}
+	}
+
+	// leave previous project
+	if prevStr := os.Getenv(hookPrevVar); prevStr != "" {
+		prev, err := decodeHookPrev(prevStr)
+		if err != nil {
+			lib.Warn.Printf("ignoring %s: %s", hookPrevVar, err)
+		}
+		for _, k := range sortedPrevKeys(prev) {
+			if v := prev[k]; v == nil {
+				f.unset(app.Stdout, k)
+				delete(base, k)
+			} else {
+				f.set(app.Stdout, k, *v)
+				base[k] = *v
+			}
+		}
+		fmt.Fprintf(app.Stderr, "bz: deactivated\n")
+	}
+
+	if project.File == "" {
+		f.unset(app.Stdout, hookKeyVar)
+		f.unset(app.Stdout, hookPrevVar)
+		return nil
+	}
+	if !allowed {
+		f.set(app.Stdout, hookKeyVar, "!"+key)
+		f.unset(app.Stdout, hookPrevVar)
+		fmt.Fprintf(app.Stderr, "bz: %s is not allowed, run `bz %sallow`\n", project.Root, CommandPrefix)
+		return nil
+	}
+
+	// enter project.  Set the key even on error so it is not retried on every prompt
+	env, key, err := hookSnapshot(app, key)
+	f.set(app.Stdout, hookKeyVar, key)
+	if err != nil {
+		f.unset(app.Stdout, hookPrevVar)
+		return err
+	}
+	if p := base["PATH"]; p != "" {
+		env["PATH"] = strings.Join([]string{env["PATH"], p}, string([]rune{os.PathListSeparator}))
+	}
+
+	prev := make(map[string]*string)
+	for _, k := range sortedKeys(env) {
+		if old, ok := base[k]; ok {
+			prev[k] = &old
+		} else {
+			prev[k] = nil
+		}
+		f.set(app.Stdout, k, env[k])
+	}
+	f.set(app.Stdout, hookPrevVar, encodeHookPrev(prev))
+	fmt.Fprintf(app.Stderr, "bz: activated %s\n", project.Root)
+	return nil
+}
+
+// hookSnapshot returns the environment of the project without the OS PATH.
+// It is cached in ~/.bz/cache/env/<key>.json to keep the prompt fast.  The
+// snapshot is created again if a dependency it uses was removed from the cache.
+// It also returns the key of the snapshot, which changes when resolving the
+// project writes the lock file
+func hookSnapshot(app *App, key string) (map[string]string, string, error) {
+	dir := filepath.Join(app.AppCtx.UserCacheDirName, lib.EnvSnapshotDirName)
+	file := filepath.Join(dir, key+".json")
+
+	env := make(map[string]string)
+	if err := utils.JsonLoad(file, &env); err == nil && hookSnapshotValid(app, env) {
+		// used recently, `:cache prune` keeps it
+		now := time.Now()
+		os.Chtimes(file, now, now)
+		return env, key, nil
+	}
+
+	engine := app.Engine()
+	rdep, err := engine.ContextFromConfigDir(app.ProjectDir())
+	if err != nil {
+		return nil, key, err
+	}
+	env = engine.DependencyEnvironment(rdep)
+	delete(env, "CURDIR") // only meaningful for commands executed by bz
+
+	// the lock file written by resolving the allowed configuration is allowed too
+	if newKey := hookProjectKey(app); newKey != key {
+		if err := hookAllow(app, newKey); err != nil {
+			return nil, key, err
+		}
+		key = newKey
+	}
+	if err := utils.MkdirIfNotExists(dir); err != nil {
+		return nil, key, err
+	}
+	b, err := json.Marshal(env)
+	if err != nil {
+		return nil, key, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, key+".json"), b, 0644); err != nil {
+		lib.Warn.Printf("unable to cache environment: %s", err)
+	}
+	return env, key, nil
+}
+
+// hookSnapshotValid returns false if a dir of the cache in the PATH of env
+// no longer exists
+func hookSnapshotValid(app *App, env map[string]string) bool {
+	cacheDir := utils.FsAbs(app.AppCtx.UserCacheDirName) + string(os.PathSeparator)
+	for _, p := range filepath.SplitList(env["PATH"]) {
+		if strings.HasPrefix(p, cacheDir) && !utils.FileExists(p) {
+			lib.Debug.Printf("hookSnapshotValid(): %s not found", p)
+			return false
+		}
+	}
+	return true
+}
+
+// hookAllowed returns true if key was allowed with `:allow`
+func hookAllowed(app *App, key string) bool {
+	return utils.FileExists(filepath.Join(app.AppCtx.UserDir, hookAllowDirName, key))
+}
+
+// hookAllow allows the hook to activate the project of app while its
+// snapshot key is key.  The file has the project dir
+func hookAllow(app *App, key string) error {
+	dir := filepath.Join(app.AppCtx.UserDir, hookAllowDirName)
+	if err := utils.MkdirIfNotExists(dir); err != nil {
+		return fmt.Errorf("hookAllow(): %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, key), []byte(app.ProjectDir()+"\n"), 0644); err != nil {
+		return fmt.Errorf("hookAllow(): %w", err)
+	}
+	return nil
+}
+
+// hookProjectKey returns the snapshot key of the project of app
+func hookProjectKey(app *App) string {
+	project := app.Project()
+	return hookSnapshotKey(project.Root, project.File, filepath.Join(project.Root, app.AppCtx.LockFileName))
+}
+
+// hookSnapshotKey identifies the environment of a project.  It changes
+// when the configuration or the lock file change
+func hookSnapshotKey(root string, files ...string) string {
+	h := sha256.New()
+	io.WriteString(h, root)
+	for _, f := range files {
+		h.Write([]byte{0})
+		if b, err := os.ReadFile(f); err == nil {
+			h.Write(b)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func encodeHookPrev(prev map[string]*string) string {
+	b, _ := json.Marshal(prev)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func decodeHookPrev(s string) (map[string]*string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	prev := make(map[string]*string)
+	return prev, json.Unmarshal(b, &prev)
+}
+
+func sortedPrevKeys(m map[string]*string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// hookTestApp returns an app in dir using cacheDir, its parent is the user dir
+func hookTestApp(cacheDir, dir string, stdout, stderr *bytes.Buffer) *App {
+	app := NewApp(stdout, stderr, bytes.NewBuffer(nil))
+	app.Options.Dir = dir
+	app.AppCtx = &model.AppContext{
+		UserDir:          filepath.Dir(cacheDir),
+		UserCacheDirName: cacheDir,
+		LockFileName:     ".bz.lock",
+		ConfigFileNames:  []string{".bz.hcl"},
+		Offline:          true,
+	}
+	return app
+}
+
+// hookTestAllow runs `:allow` in dir
+func hookTestAllow(t *testing.T, cacheDir, dir string) {
+	stdout := bytes.NewBuffer(nil)
+	assert.NoError(t, runAllow(hookTestApp(cacheDir, dir, stdout, bytes.NewBuffer(nil)), nil))
+	assert.Equal(t, "allowed "+dir+"\n", stdout.String())
+}
+
+// hookTestExport runs the hook in dir and applies the sh output to the
+// environment of the test.  It returns stderr
+func hookTestExport(t *testing.T, cacheDir, dir string) string {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	assert.NoError(t, hookExport(hookTestApp(cacheDir, dir, stdout, stderr), envFormats["sh"]))
+
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if strings.HasPrefix(line, "unset ") {
+			os.Unsetenv(strings.TrimPrefix(line, "unset "))
+		} else if strings.HasPrefix(line, "export ") {
+			k, v, _ := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+			v = strings.ReplaceAll(strings.Trim(v, "'"), `'\''`, "'")
+			os.Setenv(k, v)
+		}
+	}
+	return stderr.String()
+}
+
+func TestHookExport(t *testing.T) {
+	tmp := t.TempDir()
+	cacheDir := filepath.Join(tmp, "cache")
+	projectA := filepath.Join(tmp, "a")
+	projectB := filepath.Join(tmp, "b")
+	outside := filepath.Join(tmp, "outside")
+	for f, content := range map[string]string{
+		filepath.Join(projectA, ".bz.hcl"): `env = { FOO = "a" }`,
+		filepath.Join(projectB, ".bz.hcl"): `env = { FOO = "b", BAR = "it's b" }`,
+		filepath.Join(outside, "README"):   ``,
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(f), 0755))
+		assert.NoError(t, os.WriteFile(f, []byte(content), 0644))
+	}
+
+	// restored by the test
+	for _, k := range []string{"PATH", "FOO", "BAR", hookKeyVar, hookPrevVar} {
+		t.Setenv(k, os.Getenv(k))
+	}
+	os.Setenv("FOO", "original")
+	os.Unsetenv("BAR")
+	os.Unsetenv(hookKeyVar)
+	os.Unsetenv(hookPrevVar)
+	path := os.Getenv("PATH")
+
+	// not allowed: reported once, nothing is resolved
+	assert.Contains(t, hookTestExport(t, cacheDir, projectA), projectA+" is not allowed, run `bz :allow`")
+	assert.Equal(t, "original", os.Getenv("FOO"))
+	assert.Empty(t, hookTestExport(t, cacheDir, projectA))
+	assert.NoDirExists(t, filepath.Join(cacheDir, lib.EnvSnapshotDirName))
+
+	// enter
+	hookTestAllow(t, cacheDir, projectA)
+	hookTestAllow(t, cacheDir, projectB)
+	assert.Contains(t, hookTestExport(t, cacheDir, projectA), "activated "+projectA)
+	assert.Equal(t, "a", os.Getenv("FOO"))
+	assert.NotEmpty(t, os.Getenv(hookKeyVar))
+	assert.True(t, strings.HasSuffix(os.Getenv("PATH"), path))
+	snapshots, _ := filepath.Glob(filepath.Join(cacheDir, lib.EnvSnapshotDirName, "*.json"))
+	assert.Len(t, snapshots, 1)
+
+	// same project, nothing changes
+	assert.Empty(t, hookTestExport(t, cacheDir, projectA))
+
+	// switch, the variables of a are restored before applying b
+	stderr := hookTestExport(t, cacheDir, projectB)
+	assert.Contains(t, stderr, "deactivated")
+	assert.Contains(t, stderr, "activated "+projectB)
+	assert.Equal(t, "b", os.Getenv("FOO"))
+	assert.Equal(t, "it's b", os.Getenv("BAR"))
+	assert.True(t, strings.HasSuffix(os.Getenv("PATH"), path))
+
+	// leave
+	assert.Contains(t, hookTestExport(t, cacheDir, outside), "deactivated")
+	assert.Equal(t, "original", os.Getenv("FOO"))
+	_, ok := os.LookupEnv("BAR")
+	assert.False(t, ok)
+	assert.Equal(t, path, os.Getenv("PATH"))
+	_, ok = os.LookupEnv(hookKeyVar)
+	assert.False(t, ok)
+	_, ok = os.LookupEnv(hookPrevVar)
+	assert.False(t, ok)
+
+	// allowed again when the configuration changes
+	assert.NoError(t, os.WriteFile(filepath.Join(projectA, ".bz.hcl"), []byte(`env = { FOO = "changed" }`), 0644))
+	assert.Contains(t, hookTestExport(t, cacheDir, projectA), "is not allowed")
+	assert.Equal(t, "original", os.Getenv("FOO"))
+	hookTestAllow(t, cacheDir, projectA)
+	assert.Contains(t, hookTestExport(t, cacheDir, projectA), "activated "+projectA)
+	assert.Equal(t, "changed", os.Getenv("FOO"))
+	assert.Contains(t, hookTestExport(t, cacheDir, outside), "deactivated")
+
+	err := runAllow(hookTestApp(cacheDir, outside, bytes.NewBuffer(nil), bytes.NewBuffer(nil)), nil)
+	assert.ErrorContains(t, err, "no project configuration")
+}
+
+func TestHookPrev(t *testing.T) {
+	old := "it's old"
+	prev := map[string]*string{"A": &old, "B": nil}
+	decoded, err := decodeHookPrev(encodeHookPrev(prev))
+	assert.NoError(t, err)
+	assert.Equal(t, prev, decoded)
+	assert.Equal(t, []string{"A", "B"}, sortedPrevKeys(decoded))
+
+	_, err = decodeHookPrev("not base64!")
+	assert.Error(t, err)
+}
+
+func TestHookSnapshotValid(t *testing.T) {
+	tmp := t.TempDir()
+	app := &App{AppCtx: &model.AppContext{UserCacheDirName: tmp}}
+	existing := filepath.Join(tmp, "deps", "python", "v3", "extracted", "bin")
+	assert.NoError(t, os.MkdirAll(existing, 0755))
+	missing := filepath.Join(tmp, "deps", "python", "v2", "extracted", "bin")
+	outside := filepath.Join(t.TempDir(), "bin") // not in the cache, not checked
+
+	list := func(dirs ...string) map[string]string {
+		return map[string]string{"PATH": strings.Join(dirs, string(os.PathListSeparator))}
+	}
+	assert.True(t, hookSnapshotValid(app, list(existing, outside)))
+	assert.False(t, hookSnapshotValid(app, list(existing, missing)))
+}
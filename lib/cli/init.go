@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/model"
+)
+
+var initCmd = &Command{
+	Name:  "init",
+	Usage: "[--json] [--dep server/owner/repo[@version]]... [--package] [-i] [--force]",
+	Short: "Create .bz.hcl (or .bz.json) and .bz.lock in the current directory.",
+}
+
+func init() {
+	initCmd.Run = runInit
+	register(initCmd)
+}
+
+func runInit(app *App, args []string) error {
+	fs := app.FlagSet(initCmd)
+	asJson := fs.Bool("json", false, "create .bz.json instead of .bz.hcl")
+	var deps stringsFlag
+	fs.Var(&deps, "dep", "add a `dependency`, can be repeated")
+	pkg := fs.Bool("package", false, "create a package template with a bin/ dir and sample alias, env and triggers")
+	interactive := fs.Bool("i", false, "ask for dependencies")
+	force := fs.Bool("force", false, "overwrite an existing configuration file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+	for _, dep := range deps {
+		if _, err := model.NewCoordFromStr(dep); err != nil {
+			return usageErrorf("%s", err)
+		}
+	}
+
+	// not app.ProjectDir(): a new project may be created inside another one
+	dir := app.Options.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	engine := app.Engine()
+	if *interactive {
+		deps = append(deps, askDeps(app, engine)...)
+	}
+
+	opts := lib.InitOptions{Format: "hcl", Deps: deps, Package: *pkg, Force: *force}
+	if *asJson {
+		opts.Format = "json"
+	}
+	configFile, err := engine.Init(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(app.Stdout, "created %s\n", configFile)
+	if *pkg {
+		fmt.Fprintf(app.Stdout, "created %s\n", filepath.Join(dir, "bin"))
+	}
+	fmt.Fprintf(app.Stdout, "created %s\n", filepath.Join(dir, app.AppCtx.LockFileName))
+	return nil
+}
+
+// askDeps reads dependencies from stdin until an empty line.  Every
+// dependency is resolved to show the version that will be locked
+func askDeps(app *App, engine *lib.Engine) []string {
+	var deps []string
+	scanner := bufio.NewScanner(app.Stdin)
+	for {
+		fmt.Fprintf(app.Stderr, "dependency (server/owner/repo[@version], empty to finish): ")
+		if !scanner.Scan() {
+			fmt.Fprintln(app.Stderr)
+			return deps
+		}
+		dep := strings.TrimSpace(scanner.Text())
+		if dep == "" {
+			return deps
+		}
+
+		lc, err := engine.ResolveCoord(dep)
+		if err != nil {
+			fmt.Fprintf(app.Stderr, "  %s\n", err)
+			continue
+		}
+		fmt.Fprintf(app.Stderr, "  %s\n", lc)
+		deps = append(deps, dep)
+	}
+}
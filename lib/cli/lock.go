@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/model"
+)
+
+var lockCmd = &Command{
+	Name:  "lock",
+	Usage: "[--platform os/arch]...",
+	Short: "Re-resolve every dependency and rewrite .bz.lock without executing anything.",
+}
+
+func init() {
+	lockCmd.Run = runLock
+	register(lockCmd)
+}
+
+func runLock(app *App, args []string) error {
+	fs := app.FlagSet(lockCmd)
+	var platformsFlag stringsFlag
+	fs.Var(&platformsFlag, "platform", "also lock the assets of `os/arch`, can be repeated or comma separated (default: platforms of the previous lock)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+	var platforms []string
+	for _, p := range platformsFlag {
+		for _, p := range strings.Split(p, ",") {
+			if _, err := model.NewPlatformFromStr(p); err != nil {
+				return usageErrorf("%s", err)
+			}
+			platforms = append(platforms, p)
+		}
+	}
+
+	changes, err := app.Engine().LockForPlatforms(app.ProjectDir(), platforms)
+	if err != nil {
+		return err
+	}
+
+	printChanges(app, changes)
+	return nil
+}
+
+// printChanges prints the changes made to the lock file
+func printChanges(app *App, changes []lib.CoordChange) {
+	if len(changes) == 0 {
+		fmt.Fprintf(app.Stdout, "%s is up to date\n", app.AppCtx.LockFileName)
+		return
+	}
+	for _, c := range changes {
+		fmt.Fprintln(app.Stdout, c)
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/bazurto/bz/lib"
+)
+
+var outdatedCmd = &Command{
+	Name:  "outdated",
+	Usage: "[--json] [--all]",
+	Short: "Compare locked versions with the newest releases available.",
+}
+
+func init() {
+	outdatedCmd.Run = runOutdated
+	register(outdatedCmd)
+}
+
+func runOutdated(app *App, args []string) error {
+	fs := app.FlagSet(outdatedCmd)
+	asJson := fs.Bool("json", false, "print JSON instead of a table")
+	all := fs.Bool("all", false, "include dependencies that are up to date")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	deps, err := app.Engine().Outdated(app.ProjectDir())
+	if err != nil {
+		return err
+	}
+
+	var report []*lib.OutdatedDep
+	for _, d := range deps {
+		if *all || d.IsOutdated() {
+			report = append(report, d)
+		}
+	}
+
+	if *asJson {
+		if report == nil {
+			report = []*lib.OutdatedDep{}
+		}
+		enc := json.NewEncoder(app.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report) == 0 {
+		fmt.Fprintf(app.Stdout, "dependencies are up to date\n")
+		return nil
+	}
+	tw := tabwriter.NewWriter(app.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "DEPENDENCY\tCONSTRAINT\tLOCKED\tWANTED\tLATEST\n")
+	for _, d := range report {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Name, orDash(d.Constraint), orDash(d.Locked), orDash(d.Wanted), orDash(d.Latest))
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/model"
+)
+
+var packCmd = &Command{
+	Name:  "pack",
+	Usage: "[--version v] [--platform os/arch]... [--format tgz|zip] [--include pattern]... [--exclude pattern]... [--out dir]",
+	Short: "Lock the package and build release archives named {name}-{os}-{arch}-v{version} in dist/.",
+}
+
+func init() {
+	packCmd.Run = runPack
+	register(packCmd)
+}
+
+func runPack(app *App, args []string) error {
+	fs := app.FlagSet(packCmd)
+	var opts lib.PackOptions
+	var platforms, include, exclude stringsFlag
+	fs.StringVar(&opts.Name, "name", "", "package `name` (default: directory name)")
+	fs.StringVar(&opts.Version, "version", "", "package `version` (default: content of the VERSION file)")
+	fs.Var(&platforms, "platform", "build an archive for `os/arch`, can be repeated or comma separated.  `any` builds {name}-v{version}")
+	fs.StringVar(&opts.Format, "format", "tgz", "archive `format`: tgz or zip")
+	fs.Var(&include, "include", "ant `pattern` of files to include, can be repeated.  {os} and {arch} are replaced for every platform (default: everything)")
+	fs.Var(&exclude, "exclude", "ant `pattern` of files to exclude, can be repeated")
+	fs.StringVar(&opts.OutDir, "out", "", "output `dir` (default: dist)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+	for _, p := range platforms {
+		for _, p := range strings.Split(p, ",") {
+			if _, err := model.NewPlatformFromStr(p); err != nil && p != "any" {
+				return usageErrorf("%s", err)
+			}
+			opts.Platforms = append(opts.Platforms, p)
+		}
+	}
+	opts.Include = include
+	opts.Exclude = exclude
+
+	dir := app.ProjectDir()
+	archives, err := app.Engine().Pack(dir, opts)
+	for _, a := range archives {
+		name := a.File
+		if rel, err := filepath.Rel(dir, a.File); err == nil {
+			name = rel
+		}
+		fmt.Fprintf(app.Stdout, "%s  %s  sha256:%s\n", name, humanSize(a.Size), a.Sha256)
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/bazurto/bz/lib"
+)
+
+var publishCmd = &Command{
+	Name:  "publish",
+	Usage: "[--repo [server/]owner/repo] [--version v] [--dist dir] [--api-url url] [--force]",
+	Short: "Upload the archives of the version in dist/ to the GitHub release v{version}, creating it if needed.",
+}
+
+func init() {
+	publishCmd.Run = runPublish
+	register(publishCmd)
+}
+
+func runPublish(app *App, args []string) error {
+	fs := app.FlagSet(publishCmd)
+	var opts lib.PublishOptions
+	fs.StringVar(&opts.Repo, "repo", "", "`repository` to publish to (default: git origin remote)")
+	fs.StringVar(&opts.Version, "version", "", "release `version` (default: content of the VERSION file)")
+	fs.StringVar(&opts.DistDir, "dist", "", "`dir` with the files to upload (default: dist)")
+	fs.StringVar(&opts.APIURL, "api-url", "", "API base `url` of a GitHub compatible server (default: https://api.github.com/)")
+	fs.BoolVar(&opts.Force, "force", false, "replace assets that already exist in the release")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+
+	assets, err := app.Engine().Publish(app.ProjectDir(), opts)
+	for _, a := range assets {
+		action := "uploaded"
+		if a.Replaced {
+			action = "replaced"
+		}
+		fmt.Fprintf(app.Stdout, "%s  %s  %s  %s\n", action, a.Name, humanSize(a.Size), a.URL)
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+var removeCmd = &Command{
+	Name:  "remove",
+	Usage: "<server/owner/repo>...",
+	Short: "Remove dependencies from .bz.hcl and update .bz.lock.",
+}
+
+func init() {
+	removeCmd.Run = runRemove
+	register(removeCmd)
+}
+
+func runRemove(app *App, args []string) error {
+	fs := app.FlagSet(removeCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("missing dependency")
+	}
+
+	changes, err := app.Engine().Remove(app.ProjectDir(), fs.Args())
+	if err != nil {
+		return err
+	}
+	printChanges(app, changes)
+	return nil
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+)
+
+var treeCmd = &Command{
+	Name:  "tree",
+	Usage: "[--json|--dot]",
+	Short: "Print the transitive dependency graph with versions, cache and bin directories.",
+}
+
+func init() {
+	treeCmd.Run = runTree
+	register(treeCmd)
+}
+
+// treeNode is the JSON representation of a dependency in the graph
+type treeNode struct {
+	Coord     string      `json:"coord"`               // server/owner/repo@version
+	Name      string      `json:"name"`                // server/owner/repo
+	Version   string      `json:"version"`             //
+	Dir       string      `json:"dir"`                 // where the dependency is extracted
+	BinDir    string      `json:"binDir"`              //
+	Shared    bool        `json:"shared,omitempty"`    // same version required from more than one place
+	Duplicate bool        `json:"duplicate,omitempty"` // other versions of the same dependency are in the graph
+	Deps      []*treeNode `json:"deps,omitempty"`
+}
+
+// graphStats counts how many times each coord and each version of a
+// dependency appear in the graph
+type graphStats struct {
+	coords   map[string]int             // coord => times required
+	versions map[string]map[string]bool // name => versions
+}
+
+func newGraphStats(root *model.ResolvedDependency) *graphStats {
+	stats := &graphStats{coords: make(map[string]int), versions: make(map[string]map[string]bool)}
+	root.Walk(func(path []*model.ResolvedDependency) bool {
+		if len(path) == 1 {
+			return true // project
+		}
+		rd := path[len(path)-1]
+		stats.coords[rd.Coord.String()]++
+		name := rd.Coord.CanonicalNameNoVersion()
+		if stats.versions[name] == nil {
+			stats.versions[name] = make(map[string]bool)
+		}
+		stats.versions[name][rd.Coord.Version.Canonical()] = true
+		return true
+	})
+	return stats
+}
+
+func (s *graphStats) shared(rd *model.ResolvedDependency) bool {
+	return s.coords[rd.Coord.String()] > 1
+}
+
+// otherVersions returns the versions of rd's dependency in the graph other than rd's
+func (s *graphStats) otherVersions(rd *model.ResolvedDependency) []string {
+	var others []string
+	for v := range s.versions[rd.Coord.CanonicalNameNoVersion()] {
+		if v != rd.Coord.Version.Canonical() {
+			others = append(others, v)
+		}
+	}
+	sort.Strings(others)
+	return others
+}
+
+func runTree(app *App, args []string) error {
+	fs := app.FlagSet(treeCmd)
+	asJson := fs.Bool("json", false, "print JSON")
+	asDot := fs.Bool("dot", false, "print a Graphviz DOT graph")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("too many arguments")
+	}
+	if *asJson && *asDot {
+		return usageErrorf("--json and --dot are mutually exclusive")
+	}
+
+	root, err := app.Engine().ContextFromConfigDir(app.ProjectDir())
+	if err != nil {
+		return err
+	}
+	stats := newGraphStats(root)
+
+	switch {
+	case *asJson:
+		var deps []*treeNode
+		for _, sub := range root.Sub {
+			deps = append(deps, jsonTree(sub, stats))
+		}
+		if deps == nil {
+			deps = []*treeNode{}
+		}
+		enc := json.NewEncoder(app.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(deps)
+	case *asDot:
+		dotTree(app.Stdout, root, stats)
+		return nil
+	}
+
+	fmt.Fprintf(app.Stdout, "%s\n", root.Dir)
+	textTree(app.Stdout, root, "", stats, make(map[string]bool))
+	return nil
+}
+
+func jsonTree(rd *model.ResolvedDependency, stats *graphStats) *treeNode {
+	n := &treeNode{
+		Coord:     rd.Coord.String(),
+		Name:      rd.Coord.CanonicalNameNoVersion(),
+		Version:   rd.Coord.Version.Canonical(),
+		Dir:       rd.Dir,
+		BinDir:    rd.BinDirExpanded(),
+		Shared:    stats.shared(rd),
+		Duplicate: len(stats.otherVersions(rd)) > 0,
+	}
+	for _, sub := range rd.Sub {
+		n.Deps = append(n.Deps, jsonTree(sub, stats))
+	}
+	return n
+}
+
+// textTree prints the sub dependencies of rd.  Shared dependencies are
+// expanded only the first time they are printed
+func textTree(w io.Writer, rd *model.ResolvedDependency, prefix string, stats *graphStats, printed map[string]bool) {
+	for i, sub := range rd.Sub {
+		connector, childPrefix := "├── ", prefix+"│   "
+		if i == len(rd.Sub)-1 {
+			connector, childPrefix = "└── ", prefix+"    "
+		}
+
+		var marks []string
+		coord := sub.Coord.String()
+		if stats.shared(sub) {
+			marks = append(marks, "shared")
+		}
+		if others := stats.otherVersions(sub); len(others) > 0 {
+			marks = append(marks, fmt.Sprintf("duplicate, also %s", strings.Join(others, ", ")))
+		}
+		label := coord
+		if len(marks) > 0 {
+			label = fmt.Sprintf("%s (%s)", coord, strings.Join(marks, "; "))
+		}
+
+		if printed[coord] {
+			fmt.Fprintf(w, "%s%s%s [see above]\n", prefix, connector, label)
+			continue
+		}
+		printed[coord] = true
+
+		fmt.Fprintf(w, "%s%s%s\n", prefix, connector, label)
+		detailPrefix := childPrefix
+		if len(sub.Sub) > 0 {
+			detailPrefix += "│ "
+		} else {
+			detailPrefix += "  "
+		}
+		fmt.Fprintf(w, "%sdir: %s\n", detailPrefix, sub.Dir)
+		fmt.Fprintf(w, "%sbin: %s\n", detailPrefix, sub.BinDirExpanded())
+		textTree(w, sub, childPrefix, stats, printed)
+	}
+}
+
+// dotTree prints the graph in Graphviz DOT format.  Duplicated dependencies
+// are red
+func dotTree(w io.Writer, root *model.ResolvedDependency, stats *graphStats) {
+	fmt.Fprintf(w, "digraph bz {\n")
+	fmt.Fprintf(w, "  node [shape=box];\n")
+	fmt.Fprintf(w, "  %q [label=%q, style=bold];\n", "root", root.Dir)
+
+	nodes := make(map[string]bool)
+	edges := make(map[string]bool)
+	root.Walk(func(path []*model.ResolvedDependency) bool {
+		if len(path) == 1 {
+			return true
+		}
+		rd := path[len(path)-1]
+		id := rd.Coord.String()
+		parent := "root"
+		if len(path) > 2 {
+			parent = path[len(path)-2].Coord.String()
+		}
+
+		if !nodes[id] {
+			nodes[id] = true
+			attrs := fmt.Sprintf("label=%q", fmt.Sprintf("%s\n%s", rd.Coord.CanonicalNameNoVersion(), rd.Coord.Version.Canonical()))
+			if len(stats.otherVersions(rd)) > 0 {
+				attrs += ", color=red"
+			}
+			fmt.Fprintf(w, "  %q [%s];\n", id, attrs)
+		}
+		edge := fmt.Sprintf("  %q -> %q;\n", parent, id)
+		if !edges[edge] {
+			edges[edge] = true
+			fmt.Fprint(w, edge)
+		}
+		return true
+	})
+	fmt.Fprintf(w, "}\n")
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// treeTestGraph returns a project requiring python and curl, which both
+// require openssl 3, and openssl 1.1.1
+func treeTestGraph() *model.ResolvedDependency {
+	dep := func(repo, version string, sub ...*model.ResolvedDependency) *model.ResolvedDependency {
+		return &model.ResolvedDependency{
+			Coord:  model.LockedCoord{Server: "github.com", Owner: "o", Repo: repo, Version: model.NewVersion(version)},
+			Dir:    "/cache/" + repo + "/" + version,
+			BinDir: "$DIR/bin",
+			Sub:    sub,
+		}
+	}
+	openssl := dep("openssl", "3.0.0")
+	return &model.ResolvedDependency{
+		Dir: "/project",
+		Sub: []*model.ResolvedDependency{
+			dep("python", "3.11.1", openssl),
+			dep("curl", "8.0.0", openssl),
+			dep("openssl", "1.1.1"),
+		},
+	}
+}
+
+func TestTextTree(t *testing.T) {
+	root := treeTestGraph()
+	b := bytes.NewBuffer(nil)
+	textTree(b, root, "", newGraphStats(root), make(map[string]bool))
+	assert.Equal(t, `├── github.com/o/python@3.11.1
+│   │ dir: /cache/python/3.11.1
+│   │ bin: /cache/python/3.11.1/bin
+│   └── github.com/o/openssl@3.0.0 (shared; duplicate, also 1.1.1)
+│         dir: /cache/openssl/3.0.0
+│         bin: /cache/openssl/3.0.0/bin
+├── github.com/o/curl@8.0.0
+│   │ dir: /cache/curl/8.0.0
+│   │ bin: /cache/curl/8.0.0/bin
+│   └── github.com/o/openssl@3.0.0 (shared; duplicate, also 1.1.1) [see above]
+└── github.com/o/openssl@1.1.1 (duplicate, also 3.0.0)
+      dir: /cache/openssl/1.1.1
+      bin: /cache/openssl/1.1.1/bin
+`, b.String())
+}
+
+func TestJsonTree(t *testing.T) {
+	root := treeTestGraph()
+	stats := newGraphStats(root)
+	var nodes []*treeNode
+	for _, sub := range root.Sub {
+		nodes = append(nodes, jsonTree(sub, stats))
+	}
+	if !assert.Len(t, nodes, 3) {
+		return
+	}
+
+	python := nodes[0]
+	assert.Equal(t, "github.com/o/python@3.11.1", python.Coord)
+	assert.Equal(t, "github.com/o/python", python.Name)
+	assert.Equal(t, "3.11.1", python.Version)
+	assert.Equal(t, "/cache/python/3.11.1", python.Dir)
+	assert.Equal(t, "/cache/python/3.11.1/bin", python.BinDir)
+	assert.False(t, python.Shared)
+	assert.False(t, python.Duplicate)
+
+	// shared nodes are repeated under every parent
+	for _, parent := range nodes[:2] {
+		if assert.Len(t, parent.Deps, 1) {
+			assert.Equal(t, "github.com/o/openssl@3.0.0", parent.Deps[0].Coord)
+			assert.True(t, parent.Deps[0].Shared)
+			assert.True(t, parent.Deps[0].Duplicate)
+		}
+	}
+	assert.False(t, nodes[2].Shared)
+	assert.True(t, nodes[2].Duplicate)
+
+	b, _ := json.Marshal(nodes[2])
+	assert.JSONEq(t, `{
+		"coord": "github.com/o/openssl@1.1.1",
+		"name": "github.com/o/openssl",
+		"version": "1.1.1",
+		"dir": "/cache/openssl/1.1.1",
+		"binDir": "/cache/openssl/1.1.1/bin",
+		"duplicate": true
+	}`, string(b))
+}
+
+func TestDotTree(t *testing.T) {
+	root := treeTestGraph()
+	b := bytes.NewBuffer(nil)
+	dotTree(b, root, newGraphStats(root))
+	assert.Equal(t, `digraph bz {
+  node [shape=box];
+  "root" [label="/project", style=bold];
+  "github.com/o/python@3.11.1" [label="github.com/o/python\n3.11.1"];
+  "root" -> "github.com/o/python@3.11.1";
+  "github.com/o/openssl@3.0.0" [label="github.com/o/openssl\n3.0.0", color=red];
+  "github.com/o/python@3.11.1" -> "github.com/o/openssl@3.0.0";
+  "github.com/o/curl@8.0.0" [label="github.com/o/curl\n8.0.0"];
+  "root" -> "github.com/o/curl@8.0.0";
+  "github.com/o/curl@8.0.0" -> "github.com/o/openssl@3.0.0";
+  "github.com/o/openssl@1.1.1" [label="github.com/o/openssl\n1.1.1", color=red];
+  "root" -> "github.com/o/openssl@1.1.1";
+}
+`, b.String())
+}
+
+func TestTreeFormats(t *testing.T) {
+	code, _, stderr := runApp(":tree", "--json", "--dot")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, "mutually exclusive")
+}
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+var updateCmd = &Command{
+	Name:  "update",
+	Usage: "[--major] [server/owner/repo...]",
+	Short: "Upgrade the named dependencies (all if none given) within their version constraints, keeping the others pinned.",
+}
+
+func init() {
+	updateCmd.Run = runUpdate
+	register(updateCmd)
+}
+
+func runUpdate(app *App, args []string) error {
+	fs := app.FlagSet(updateCmd)
+	major := fs.Bool("major", false, "upgrade past the version constraint and rewrite it in .bz.hcl")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	changes, err := app.Engine().Update(app.ProjectDir(), fs.Args(), *major)
+	if err != nil {
+		return err
+	}
+	printChanges(app, changes)
+	return nil
+}
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/bazurto/bz/lib"
+	"github.com/bazurto/bz/lib/model"
+)
+
+var whyCmd = &Command{
+	Name:  "why",
+	Usage: "<server/owner/repo[@version]|command|ENV_VAR>",
+	Short: "Explain which dependency brings in a dependency, provides a command or sets a variable.",
+}
+
+var envVarRegexp = regexp.MustCompile(`^[A-Z_][A-Z0-9_.]*$`)
+
+func init() {
+	whyCmd.Run = runWhy
+	register(whyCmd)
+}
+
+func runWhy(app *App, args []string) error {
+	fs := app.FlagSet(whyCmd)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("expected one argument")
+	}
+	what := fs.Arg(0)
+
+	engine := app.Engine()
+	root, err := engine.ContextFromConfigDir(app.ProjectDir())
+	if err != nil {
+		return err
+	}
+	os.Setenv("BZ_PROJECT_DIR", root.Dir) // also have to be set in lib/model/resolveddependency.go
+
+	switch {
+	case strings.Contains(what, "/") && !strings.HasPrefix(what, ".") && !filepath.IsAbs(what):
+		return whyCoord(app, root, what)
+	case envVarRegexp.MatchString(what):
+		return whyEnv(app, engine, root, what)
+	}
+	return whyCommand(app, engine, root, what)
+}
+
+// depLabel names rd for the user.  The project is the root of the graph
+func depLabel(root, rd *model.ResolvedDependency) string {
+	if rd == root {
+		return fmt.Sprintf("project (%s)", root.Dir)
+	}
+	return rd.Coord.String()
+}
+
+// whyCoord prints every path from the project to the dependency
+func whyCoord(app *App, root *model.ResolvedDependency, coord string) error {
+	name, version, _ := strings.Cut(coord, "@")
+	found := false
+	root.Walk(func(path []*model.ResolvedDependency) bool {
+		rd := path[len(path)-1]
+		if rd == root {
+			return true
+		}
+		if rd.Coord.CanonicalNameNoVersion() != name && rd.Coord.Server+rd.Coord.Repo != name {
+			return true
+		}
+		if version != "" && strings.TrimPrefix(version, "v") != rd.Coord.Version.Canonical() {
+			return true
+		}
+
+		found = true
+		var labels []string
+		for _, p := range path {
+			labels = append(labels, depLabel(root, p))
+		}
+		fmt.Fprintf(app.Stdout, "%s\n", strings.Join(labels, "\n  -> "))
+		return true
+	})
+
+	if !found {
+		return fmt.Errorf("`%s` is not in the dependency graph", coord)
+	}
+	return nil
+}
+
+// whyCommand prints the aliases applied to cmd and which bin dir in PATH
+// provides the executable
+func whyCommand(app *App, engine *lib.Engine, root *model.ResolvedDependency, cmd string) error {
+	matches, args := root.AliasChain([]string{cmd})
+	for _, m := range matches {
+		fmt.Fprintf(app.Stdout, "alias `%s` defined by %s\n", m.Name, depLabel(root, m.Dep))
+		fmt.Fprintf(app.Stdout, "  %s => %s\n", m.Value, strings.Join(m.Args, " "))
+	}
+	if len(matches) > 1 {
+		fmt.Fprintf(app.Stdout, "  (aliases are applied from the project down to its dependencies)\n")
+	}
+
+	prog := args[0]
+	if strings.ContainsRune(prog, os.PathSeparator) {
+		fmt.Fprintf(app.Stdout, "`%s` is executed by path\n", prog)
+		return nil
+	}
+
+	// bin dir => dependency
+	binDirs := make(map[string]*model.ResolvedDependency)
+	root.Walk(func(path []*model.ResolvedDependency) bool {
+		rd := path[len(path)-1]
+		if _, ok := binDirs[rd.BinDirExpanded()]; !ok {
+			binDirs[rd.BinDirExpanded()] = rd
+		}
+		return true
+	})
+
+	var found []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(engine.Environment(root)["PATH"]) {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		for _, candidate := range executableNames(prog) {
+			f := filepath.Join(dir, candidate)
+			if stat, err := os.Stat(f); err != nil || stat.IsDir() || (stat.Mode()&0111 == 0 && runtime.GOOS != "windows") {
+				continue
+			}
+			provider := "system PATH"
+			if rd, ok := binDirs[dir]; ok {
+				provider = depLabel(root, rd)
+			}
+			found = append(found, fmt.Sprintf("%s (%s)", f, provider))
+			break
+		}
+	}
+
+	if len(found) == 0 {
+		if len(matches) == 0 {
+			return fmt.Errorf("`%s` is neither an alias nor an executable in PATH", cmd)
+		}
+		fmt.Fprintf(app.Stdout, "`%s` not found in PATH\n", prog)
+		return nil
+	}
+	fmt.Fprintf(app.Stdout, "`%s` resolves to %s\n", prog, found[0])
+	for _, f := range found[1:] {
+		fmt.Fprintf(app.Stdout, "  shadows %s\n", f)
+	}
+	return nil
+}
+
+// executableNames returns the file names that can execute prog
+func executableNames(prog string) []string {
+	if runtime.GOOS != "windows" || filepath.Ext(prog) != "" {
+		return []string{prog}
+	}
+	var names []string
+	for _, ext := range filepath.SplitList(os.Getenv("PATHEXT")) {
+		names = append(names, prog+strings.ToLower(ext))
+	}
+	return names
+}
+
+// whyEnv prints which dependency sets key and what it overrides
+func whyEnv(app *App, engine *lib.Engine, root *model.ResolvedDependency, key string) error {
+	if key == "PATH" {
+		fmt.Fprintf(app.Stdout, "PATH is the bin dir of every dependency, in this order, followed by the OS PATH:\n")
+		seen := make(map[string]bool)
+		root.Walk(func(path []*model.ResolvedDependency) bool {
+			rd := path[len(path)-1]
+			if seen[rd.BinDirExpanded()] {
+				return true
+			}
+			seen[rd.BinDirExpanded()] = true
+			fmt.Fprintf(app.Stdout, "  %s (%s)\n", rd.BinDirExpanded(), depLabel(root, rd))
+			return true
+		})
+		return nil
+	}
+
+	settings := root.EnvSettings(key)
+	osValue, inOs := os.LookupEnv(key)
+	if len(settings) == 0 {
+		if inOs {
+			fmt.Fprintf(app.Stdout, "%s=%s is not set by bz, it comes from the OS environment\n", key, osValue)
+			return nil
+		}
+		return fmt.Errorf("`%s` is not set", key)
+	}
+
+	winner := settings[len(settings)-1]
+	fmt.Fprintf(app.Stdout, "%s=%s\n", key, winner.Value)
+	fmt.Fprintf(app.Stdout, "  set by %s (%s)\n", depLabel(root, winner.Dep), envSourceDescription(winner))
+	seen := map[string]bool{depLabel(root, winner.Dep) + "=" + winner.Value: true}
+	for i := len(settings) - 2; i >= 0; i-- {
+		s := settings[i]
+		// a dependency required from more than one place sets the same value
+		id := depLabel(root, s.Dep) + "=" + s.Value
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		fmt.Fprintf(app.Stdout, "  overrides %s=%s from %s (%s)\n", key, s.Value, depLabel(root, s.Dep), envSourceDescription(s))
+	}
+	if inOs && osValue != winner.Value && key != "BZ_PROJECT_DIR" {
+		fmt.Fprintf(app.Stdout, "  overrides %s=%s from the OS environment\n", key, osValue)
+	}
+	return nil
+}
+
+func envSourceDescription(s model.EnvSetting) string {
+	switch s.Source {
+	case model.EnvSourceExport:
+		return "`env` section"
+	case model.EnvSourceImplicit:
+		return "implicit _DIR/_BINDIR variable"
+	}
+	return "built-in variable"
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// whyTestGraph returns a project requiring app and python, app requires
+// python too.  python provides the python3 and tool executables, app
+// provides tool
+func whyTestGraph(t *testing.T) (*App, *bytes.Buffer, *model.ResolvedDependency) {
+	tmp := t.TempDir()
+	dep := func(repo, version string, sub ...*model.ResolvedDependency) *model.ResolvedDependency {
+		return &model.ResolvedDependency{
+			Coord: model.LockedCoord{Server: "github.com", Owner: "o", Repo: repo, Version: model.NewVersion(version)},
+			Dir:   filepath.Join(tmp, repo),
+			Sub:   sub,
+		}
+	}
+	python := dep("python", "3.11.1")
+	python.Alias = map[string]string{"python": "$DIR/bin/python3"}
+	python.Exports = map[string]string{"WHY_TEST_VAR": "python"}
+	app := dep("app", "1.0.0", python)
+	app.Exports = map[string]string{"WHY_TEST_VAR": "app"}
+	root := &model.ResolvedDependency{
+		Dir:     filepath.Join(tmp, "project"),
+		Exports: map[string]string{"WHY_TEST_VAR": "project"},
+		Alias:   map[string]string{"py": "python -u"},
+		Sub:     []*model.ResolvedDependency{app, python},
+	}
+	for _, f := range []string{"python/bin/python3", "python/bin/tool", "app/bin/tool"} {
+		f = filepath.Join(tmp, f)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(f), 0755))
+		assert.NoError(t, os.WriteFile(f, []byte("#!/bin/sh\n"), 0755))
+	}
+
+	stdout := bytes.NewBuffer(nil)
+	a := NewApp(stdout, bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	a.AppCtx = &model.AppContext{UserCacheDirName: filepath.Join(tmp, "cache"), LockFileName: ".bz.lock"}
+	return a, stdout, root
+}
+
+// whyTestOutput returns stdout with the temp dir of the graph replaced by TMP
+func whyTestOutput(root *model.ResolvedDependency, stdout *bytes.Buffer) string {
+	return strings.ReplaceAll(stdout.String(), filepath.Dir(root.Dir), "TMP")
+}
+
+func TestWhyCoord(t *testing.T) {
+	app, stdout, root := whyTestGraph(t)
+	assert.NoError(t, whyCoord(app, root, "github.com/o/python"))
+	assert.Equal(t, `project (TMP/project)
+  -> github.com/o/app@1.0.0
+  -> github.com/o/python@3.11.1
+project (TMP/project)
+  -> github.com/o/python@3.11.1
+`, whyTestOutput(root, stdout))
+
+	stdout.Reset()
+	assert.NoError(t, whyCoord(app, root, "github.com/o/app@1.0.0"))
+	assert.Equal(t, `project (TMP/project)
+  -> github.com/o/app@1.0.0
+`, whyTestOutput(root, stdout))
+
+	assert.Error(t, whyCoord(app, root, "github.com/o/python@2.7.18"))
+	assert.Error(t, whyCoord(app, root, "github.com/o/missing"))
+}
+
+func TestWhyEnv(t *testing.T) {
+	app, stdout, root := whyTestGraph(t)
+	t.Setenv("WHY_TEST_VAR", "os")
+	assert.NoError(t, whyEnv(app, app.Engine(), root, "WHY_TEST_VAR"))
+	assert.Equal(t, strings.Join([]string{
+		"WHY_TEST_VAR=project",
+		"  set by project (TMP/project) (`env` section)",
+		"  overrides WHY_TEST_VAR=python from github.com/o/python@3.11.1 (`env` section)",
+		"  overrides WHY_TEST_VAR=app from github.com/o/app@1.0.0 (`env` section)",
+		"  overrides WHY_TEST_VAR=os from the OS environment",
+		"",
+	}, "\n"), whyTestOutput(root, stdout))
+
+	stdout.Reset()
+	assert.NoError(t, whyEnv(app, app.Engine(), root, "PYTHON_DIR"))
+	assert.Equal(t, `PYTHON_DIR=TMP/python
+  set by github.com/o/python@3.11.1 (implicit _DIR/_BINDIR variable)
+`, whyTestOutput(root, stdout))
+
+	assert.Error(t, whyEnv(app, app.Engine(), root, "WHY_TEST_MISSING"))
+}
+
+func TestWhyCommand(t *testing.T) {
+	app, stdout, root := whyTestGraph(t)
+	assert.NoError(t, whyCommand(app, app.Engine(), root, "py"))
+	assert.Equal(t, strings.Join([]string{
+		"alias `py` defined by project (TMP/project)",
+		"  python -u => python -u",
+		"alias `python` defined by github.com/o/python@3.11.1",
+		"  $DIR/bin/python3 => TMP/python/bin/python3 -u",
+		"  (aliases are applied from the project down to its dependencies)",
+		"`TMP/python/bin/python3` is executed by path",
+		"",
+	}, "\n"), whyTestOutput(root, stdout))
+
+	stdout.Reset()
+	assert.NoError(t, whyCommand(app, app.Engine(), root, "tool"))
+	assert.Equal(t, strings.Join([]string{
+		"`tool` resolves to TMP/app/bin/tool (github.com/o/app@1.0.0)",
+		"  shadows TMP/python/bin/tool (github.com/o/python@3.11.1)",
+		"",
+	}, "\n"), whyTestOutput(root, stdout))
+
+	assert.Error(t, whyCommand(app, app.Engine(), root, "why-test-missing"))
+}
@@ -0,0 +1,397 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// Policies for dependencies required at more than one version in the graph
+const (
+	ConflictsMVS     = "mvs"     // minimal version selection: the highest version required
+	ConflictsHighest = "highest" // the newest release compatible with the highest version required
+	ConflictsFail    = "fail"    // fail listing the conflicts
+	ConflictOverride = "override"
+	ConflictReplace  = "replace"
+)
+
+// maxGraphIterations bounds how many times the graph is resolved again
+// while conflicts are being settled
+const maxGraphIterations = 10
+
+// DependencyConflict is a dependency required at more than one version in
+// the graph, or overridden or replaced by the project, and how it was settled
+type DependencyConflict struct {
+	Name        string            `json:"name"`                  // server/owner/repo
+	Requested   []ConflictRequest `json:"requested"`             // every version required in the graph
+	Selected    string            `json:"selected"`              // version used in the whole graph
+	Replacement string            `json:"replacement,omitempty"` // dependency used instead, server/owner/repo@version
+	Reason      string            `json:"reason"`                // mvs, highest, override or replace
+	Warning     string            `json:"warning,omitempty"`     // why the selection may break a dependency
+}
+
+// ConflictRequest is a version of a dependency required by another one
+type ConflictRequest struct {
+	Version string `json:"version"`
+	By      string `json:"by"` // server/owner/repo@version, `project` for the configuration file
+}
+
+// graphResolution holds what is shared by every dependency while resolving
+// the graph of a project
+type graphResolution struct {
+	pins       map[string]*model.LockedNode             // resolved section of the root lock file
+	selected   map[string]*model.LockedCoord            // name => coord used in the whole graph
+	overridden map[string]bool                          // name => selected by the overrides of the project
+	replaced   map[string]*model.LockedCoord            // name => dependency used instead
+	requests   map[string]map[string]*model.LockedCoord // name => by => coord required
+}
+
+func newGraphResolution(pins map[string]*model.LockedNode) *graphResolution {
+	return &graphResolution{
+		pins:       pins,
+		selected:   make(map[string]*model.LockedCoord),
+		overridden: make(map[string]bool),
+		replaced:   make(map[string]*model.LockedCoord),
+		requests:   make(map[string]map[string]*model.LockedCoord),
+	}
+}
+
+// require records that parent requires lc and returns the coord to use
+// instead of lc
+func (g *graphResolution) require(parent, lc *model.LockedCoord) *model.LockedCoord {
+	name := lc.CanonicalNameNoVersion()
+	by := parent.String()
+	if by == rootCoord().String() {
+		by = "project"
+	}
+	if g.requests[name] == nil {
+		g.requests[name] = make(map[string]*model.LockedCoord)
+	}
+	if _, ok := g.requests[name][by]; !ok {
+		g.requests[name][by] = lc
+	}
+
+	if replacement, ok := g.replaced[name]; ok {
+		return replacement
+	}
+	if selected, ok := g.selected[name]; ok {
+		return selected
+	}
+	return lc
+}
+
+// conflict returns the conflict for name or nil if name is required at a
+// single version and it is neither overridden nor replaced
+func (g *graphResolution) conflict(name string) *DependencyConflict {
+	c := &DependencyConflict{Name: name}
+	versions := make(map[string]bool)
+	for by, lc := range g.requests[name] {
+		c.Requested = append(c.Requested, ConflictRequest{Version: lc.Version.Canonical(), By: by})
+		versions[lc.Version.Canonical()] = true
+	}
+	if len(versions) < 2 && !g.overridden[name] && g.replaced[name] == nil {
+		return nil
+	}
+	sort.Slice(c.Requested, func(i, j int) bool {
+		a, b := model.NewVersion(c.Requested[i].Version), model.NewVersion(c.Requested[j].Version)
+		if r := a.Compare(b); r != 0 {
+			return r > 0
+		}
+		return c.Requested[i].By < c.Requested[j].By
+	})
+	if replacement, ok := g.replaced[name]; ok {
+		c.Reason = ConflictReplace
+		c.Selected = replacement.Version.Canonical()
+		c.Replacement = replacement.String()
+	} else if g.overridden[name] {
+		c.Reason = ConflictOverride
+		c.Selected = g.selected[name].Version.Canonical()
+	}
+	return c
+}
+
+// highestRequested returns the highest version of name required in the graph
+func (g *graphResolution) highestRequested(name string) *model.LockedCoord {
+	var bys []string
+	for by := range g.requests[name] {
+		bys = append(bys, by)
+	}
+	sort.Strings(bys) // stable choice when several coords have the same version
+
+	var highest *model.LockedCoord
+	for _, by := range bys {
+		lc := g.requests[name][by]
+		if highest == nil || lc.Version.Compare(highest.Version) > 0 {
+			highest = lc
+		}
+	}
+	return highest
+}
+
+// pinned returns the highest node of the resolved section of the root lock
+// file for name that satisfies constraint
+func (g *graphResolution) pinned(name string, constraint model.VersionConstraint) *model.LockedCoord {
+	var best *model.LockedCoord
+	for _, n := range g.pins {
+		if n.CanonicalNameNoVersion() != name || !constraint.Matches(n.Version) {
+			continue
+		}
+		if best == nil || n.Version.Compare(best.Version) > 0 {
+			lc := n.LockedCoord
+			best = &lc
+		}
+	}
+	return best
+}
+
+// resolveGraph resolves the dependency graph of lcc, the lock of the project
+// in dir.  The replacements of lcc are used instead of the dependencies they
+// replace.  Dependencies required at more than one version are settled with
+// the conflicts policy and the overrides of lcc, and the graph is resolved
+// again until every dependency has a single version
+func (o *Engine) resolveGraph(dir string, lcc *model.LockedConfigContent) (*model.ResolvedDependency, []*DependencyConflict, error) {
+	policy := lcc.Conflicts
+	if policy == "" {
+		policy = ConflictsMVS
+	}
+	if policy != ConflictsMVS && policy != ConflictsHighest && policy != ConflictsFail {
+		return nil, nil, fmt.Errorf("unknown conflicts policy `%s`, expected %s, %s or %s", policy, ConflictsMVS, ConflictsHighest, ConflictsFail)
+	}
+
+	g := newGraphResolution(lockedNodesByName(lcc.Resolved))
+	for name, replacement := range lcc.Replace {
+		lc, err := o.resolveReplacement(g, dir, replacement)
+		if err != nil {
+			return nil, nil, fmt.Errorf("replace `%s`: %w", name, err)
+		}
+		name, _, _ = strings.Cut(name, "@")
+		g.replaced[name] = lc
+	}
+	for name, version := range lcc.Overrides {
+		lc, err := o.resolveOverride(g, name, version)
+		if err != nil {
+			return nil, nil, err
+		}
+		g.selected[lc.CanonicalNameNoVersion()] = lc
+		g.overridden[lc.CanonicalNameNoVersion()] = true
+	}
+
+	for i := 0; i < maxGraphIterations; i++ {
+		// the requests of a pass are those of the graph it resolves, a
+		// version selected in a previous pass may not require them anymore
+		g.requests = make(map[string]map[string]*model.LockedCoord)
+		rd, err := o.resolvedDependencyFromConfigContext(dir, rootCoord(), lcc, utils.NewCircularDependencyDetector(), g)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		conflicts, changed, err := o.settleConflicts(g, policy)
+		if err != nil {
+			return nil, conflicts, err
+		}
+		if !changed {
+			for _, c := range conflicts {
+				if c.Warning != "" {
+					Warn.Printf("%s@%s selected: %s", c.Name, c.Selected, c.Warning)
+				}
+			}
+			return rd, conflicts, nil
+		}
+		Debug.Printf("resolveGraph(): conflicts settled, resolving the graph again")
+	}
+	return nil, nil, fmt.Errorf("dependency conflicts in %s did not settle after %d iterations", dir, maxGraphIterations)
+}
+
+// resolveOverride resolves version, the override of name in the project.
+// Versions in the resolved section of the lock file are used first
+func (o *Engine) resolveOverride(g *graphResolution, name, version string) (*model.LockedCoord, error) {
+	fc, err := newFuzzyCoord(fmt.Sprintf("%s@%s", name, version), nil, o.appCtx.Prerelease)
+	if err != nil {
+		return nil, fmt.Errorf("overrides: %w", err)
+	}
+	if lc := g.pinned(fc.CanonicalNameNoVersion(), fc.Constraint()); lc != nil {
+		return lc, nil
+	}
+	return o.resolveCoord(fc)
+}
+
+// resolveReplacement resolves replacement, a dependency (server/owner/repo@version)
+// or a local path, absolute or relative to the project in dir.  Versions in the
+// resolved section of the lock file are used first
+func (o *Engine) resolveReplacement(g *graphResolution, dir, replacement string) (*model.LockedCoord, error) {
+	if isLocalPath(replacement) {
+		path := replacement
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(utils.FsAbs(dir), path)
+		}
+		replacement = "local" + filepath.ToSlash(filepath.Clean(path))
+	}
+	fc, err := newFuzzyCoord(replacement, nil, o.appCtx.Prerelease)
+	if err != nil {
+		return nil, err
+	}
+	if fc.Server != "local" && fc.Server != "local.local" {
+		if lc := g.pinned(fc.CanonicalNameNoVersion(), fc.Constraint()); lc != nil {
+			return lc, nil
+		}
+	}
+	return o.resolveCoord(fc)
+}
+
+// isLocalPath returns true if s is a path instead of a dependency: /path,
+// ./path, ../path
+func isLocalPath(s string) bool {
+	return filepath.IsAbs(s) || s == "." || s == ".." ||
+		strings.HasPrefix(s, "./") || strings.HasPrefix(s, "../") ||
+		strings.HasPrefix(s, "."+string(filepath.Separator)) || strings.HasPrefix(s, ".."+string(filepath.Separator))
+}
+
+// settleConflicts selects a single version for every dependency required at
+// more than one version.  It returns true if a selection changed and the
+// graph has to be resolved again.  The mvs and highest policies also settle
+// versions of different major versions, with a warning on the conflict
+func (o *Engine) settleConflicts(g *graphResolution, policy string) ([]*DependencyConflict, bool, error) {
+	var names []string
+	for name := range g.requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changed := false
+	for name := range g.selected {
+		if _, ok := g.requests[name]; !ok && !g.overridden[name] {
+			delete(g.selected, name) // not required anymore
+		}
+	}
+
+	var conflicts []*DependencyConflict
+	var unsettled []*DependencyConflict
+	for _, name := range names {
+		c := g.conflict(name)
+		if c == nil {
+			// required at a single version now, the selection of a previous
+			// pass is stale
+			if lc, ok := g.selected[name]; ok && !g.overridden[name] {
+				delete(g.selected, name)
+				changed = changed || lc.String() != g.highestRequested(name).String()
+			}
+			continue
+		}
+		conflicts = append(conflicts, c)
+		if c.Reason != "" {
+			continue // settled by the project
+		}
+		if policy == ConflictsFail {
+			unsettled = append(unsettled, c)
+			continue
+		}
+
+		var lc *model.LockedCoord
+		switch policy {
+		case ConflictsMVS:
+			lc = g.highestRequested(name)
+		case ConflictsHighest:
+			var err error
+			if lc, err = o.resolveHighestCompatible(g, name); err != nil {
+				return conflicts, false, err
+			}
+		}
+		c.Reason = policy
+		c.Selected = lc.Version.Canonical()
+		if !sameMajor(c.Requested) {
+			c.Warning = "required at different major versions"
+		}
+		if old, ok := g.selected[name]; !ok || old.String() != lc.String() {
+			g.selected[name] = lc
+			changed = true
+		}
+	}
+
+	if len(unsettled) > 0 {
+		return conflicts, false, conflictsError(policy, unsettled)
+	}
+	return conflicts, changed, nil
+}
+
+// resolveHighestCompatible returns the newest release of name compatible
+// with the highest version required in the graph (same major version).
+// Versions in the resolved section of the lock file are used first
+func (o *Engine) resolveHighestCompatible(g *graphResolution, name string) (*model.LockedCoord, error) {
+	highest := g.highestRequested(name)
+	if highest.Server == "local" || highest.Server == "local.local" {
+		return highest, nil // not versioned
+	}
+	fc, err := newFuzzyCoord(fmt.Sprintf("%s@^%s", name, highest.Version.Canonical()), nil, o.appCtx.Prerelease)
+	if err != nil {
+		return nil, err
+	}
+	if lc := g.pinned(name, fc.Constraint()); lc != nil && lc.Version.Compare(highest.Version) >= 0 {
+		return lc, nil
+	}
+	lc, err := o.resolveCoord(fc)
+	if err != nil {
+		return nil, err
+	}
+	if lc.Version.Compare(highest.Version) < 0 {
+		return highest, nil
+	}
+	return lc, nil
+}
+
+// sameMajor returns true if every version requested has the same major
+// version
+func sameMajor(requested []ConflictRequest) bool {
+	first := model.NewVersion(requested[0].Version)
+	for _, r := range requested[1:] {
+		v := model.NewVersion(r.Version)
+		if v.Major() != first.Major() {
+			return false
+		}
+	}
+	return true
+}
+
+// conflictsError lists the conflicts that policy does not settle
+func conflictsError(policy string, conflicts []*DependencyConflict) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dependencies required at more than one version (conflicts = \"%s\"):\n", policy)
+	for _, c := range conflicts {
+		var requested []string
+		for _, r := range c.Requested {
+			requested = append(requested, fmt.Sprintf("%s (%s)", r.Version, r.By))
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", c.Name, strings.Join(requested, ", "))
+	}
+	fmt.Fprintf(&b, "add them to `overrides` to select a version")
+	return fmt.Errorf("%s", b.String())
+}
+
+// Conflicts resolves the dependency graph of the project in dir and returns
+// every dependency required at more than one version, overridden or replaced,
+// with the version selected.  The lock file is neither written nor
+// registered: a stale one is resolved from the configuration, or fails in
+// frozen mode
+func (o *Engine) Conflicts(dir string) ([]*DependencyConflict, error) {
+	status, err := o.lockStatus(dir)
+	if err != nil {
+		return nil, err
+	}
+	lcc := status.Lock
+	if status.Stale() {
+		if o.appCtx.Frozen {
+			return nil, status.frozenError()
+		}
+		if lcc, err = o.readFuzzyConfigContentFromDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	_, conflicts, err := o.resolveGraph(dir, lcc)
+	return conflicts, err
+}
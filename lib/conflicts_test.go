@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+	"github.com/stretchr/testify/assert"
+)
+
+// versionResolver resolves example.com dependencies to the highest version
+// in versions[repo] matching the constraint.  Every version of a package is
+// extracted to dirs[repo@version]
+type versionResolver struct {
+	versions  map[string][]string
+	dirs      map[string]string
+	downloads map[string]int // repo@version => times downloaded
+}
+
+func (r *versionResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord, error) {
+	if c.Server != "example.com" {
+		return nil, nil
+	}
+	var versions []model.Version
+	for _, v := range r.versions[c.Repo] {
+		versions = append(versions, model.NewVersion(v))
+	}
+	i := c.Constraint().Best(versions)
+	if i < 0 {
+		return nil, fmt.Errorf("%s not found", c)
+	}
+	return &model.LockedCoord{Server: c.Server, Owner: c.Owner, Repo: c.Repo, Version: versions[i]}, nil
+}
+
+func (r *versionResolver) DownloadResolvedCoord(c *model.LockedCoord) (string, error, bool) {
+	if c.Server != "example.com" {
+		return "", nil, false
+	}
+	r.downloads[c.Repo+"@"+c.Version.Canonical()]++
+	return r.dirs[c.Repo+"@"+c.Version.Canonical()], nil, true
+}
+
+// conflictsTestEngine creates a project with config.  a@1.0.0 requires
+// c@1.2.0, b@1.0.0 requires c@1.3.0, d@1.0.0 requires b@1.1.0 and e@1.0.0
+// requires g@2.0.0
+func conflictsTestEngine(t *testing.T, config string) (*Engine, string) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+		ConfigFileNames:  []string{".bz.hcl"},
+	})
+	r := &versionResolver{
+		versions: map[string][]string{"a": {"1.0.0"}, "b": {"1.0.0", "1.1.0"}, "c": {"1.0.0", "1.2.0", "1.3.0"},
+			"d": {"1.0.0"}, "e": {"1.0.0"}, "g": {"1.0.0", "2.0.0"}, "fork": {"1.2.0-patched"}},
+		dirs:      make(map[string]string),
+		downloads: make(map[string]int),
+	}
+	for coord, lock := range map[string]string{
+		"a@1.0.0":            `{"deps":[{"server":"example.com","owner":"o","repo":"c","version":"1.2.0"}]}`,
+		"b@1.0.0":            `{"deps":[{"server":"example.com","owner":"o","repo":"c","version":"1.3.0"}]}`,
+		"b@1.1.0":            `{}`,
+		"d@1.0.0":            `{"deps":[{"server":"example.com","owner":"o","repo":"b","version":"1.1.0"}]}`,
+		"e@1.0.0":            `{"deps":[{"server":"example.com","owner":"o","repo":"g","version":"2.0.0"}]}`,
+		"c@1.0.0":            `{}`,
+		"c@1.2.0":            `{}`,
+		"c@1.3.0":            `{}`,
+		"g@1.0.0":            `{}`,
+		"g@2.0.0":            `{}`,
+		"fork@1.2.0-patched": `{}`,
+		"localc":             `{}`,
+	} {
+		r.dirs[coord] = filepath.Join(tmp, coord)
+		writeCacheTestFile(t, filepath.Join(tmp, coord, ".bz.lock"), lock)
+	}
+	engine.AddResolver(r)
+	engine.AddResolver(resolver.NewLocalDevResolver(nil))
+
+	project := filepath.Join(tmp, "project")
+	writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), config)
+	return engine, project
+}
+
+func TestConflictsPolicies(t *testing.T) {
+	d := map[string]string{
+		``:                      "1.2.0",
+		`conflicts = "mvs"`:     "1.2.0",
+		`conflicts = "highest"`: "1.3.0",
+		`overrides = { "example.com/o/c" = "1.0" }`: "1.0.0",
+	}
+	for policy, expected := range d {
+		engine, project := conflictsTestEngine(t, policy+"\n"+`deps = ["example.com/o/c@1.0", "example.com/o/a@1"]`)
+		_, err := engine.Lock(project)
+		assert.NoError(t, err, policy)
+
+		// a single version in the whole graph
+		lcc, err := engine.lockedConfigContentFromDir(project)
+		assert.NoError(t, err)
+		var versions []string
+		for _, n := range lcc.Resolved {
+			if n.Repo == "c" {
+				versions = append(versions, n.Version.Canonical())
+			}
+		}
+		assert.Equal(t, []string{expected}, versions, policy)
+		assert.Equal(t, "1.0.0", lcc.Deps[0].Version.Canonical(), policy) // as required by the configuration
+
+		conflicts, err := engine.Conflicts(project)
+		assert.NoError(t, err)
+		assert.Len(t, conflicts, 1)
+		assert.Equal(t, "example.com/o/c", conflicts[0].Name)
+		assert.Equal(t, expected, conflicts[0].Selected, policy)
+		assert.Equal(t, []ConflictRequest{
+			{Version: "1.2.0", By: "example.com/o/a@1.0.0"},
+			{Version: "1.0.0", By: "project"},
+		}, conflicts[0].Requested)
+	}
+}
+
+func TestConflictsFail(t *testing.T) {
+	engine, project := conflictsTestEngine(t, `
+conflicts = "fail"
+deps = ["example.com/o/c@1.0", "example.com/o/a@1"]
+`)
+	_, err := engine.Lock(project)
+	assert.ErrorContains(t, err, "example.com/o/c: 1.2.0 (example.com/o/a@1.0.0), 1.0.0 (project)")
+
+	engine, project = conflictsTestEngine(t, `
+conflicts = "nope"
+deps = ["example.com/o/c@1.0"]
+`)
+	_, err = engine.Lock(project)
+	assert.ErrorContains(t, err, "unknown conflicts policy `nope`")
+
+	// no conflicts
+	engine, project = conflictsTestEngine(t, `
+conflicts = "fail"
+deps = ["example.com/o/c@1.2", "example.com/o/a@1"]
+`)
+	conflicts, err := engine.Conflicts(project)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
+
+func TestConflictsDropped(t *testing.T) {
+	// b@1.0.0 requires c@1.3.0 but mvs selects b@1.1.0, which does not
+	engine, project := conflictsTestEngine(t, `deps = ["example.com/o/b@1.0", "example.com/o/d@1", "example.com/o/c@1.0"]`)
+	_, err := engine.Lock(project)
+	assert.NoError(t, err)
+
+	lcc, err := engine.lockedConfigContentFromDir(project)
+	assert.NoError(t, err)
+	var nodes []string
+	for _, n := range lcc.Resolved {
+		nodes = append(nodes, n.String())
+	}
+	assert.ElementsMatch(t, []string{"example.com/o/b@1.1.0", "example.com/o/d@1.0.0", "example.com/o/c@1.0.0"}, nodes)
+
+	conflicts, err := engine.Conflicts(project)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "example.com/o/b", conflicts[0].Name)
+	assert.Equal(t, "1.1.0", conflicts[0].Selected)
+}
+
+func TestConflictsMajor(t *testing.T) {
+	// e@1.0.0 requires g@2.0.0: the highest version is selected with a warning
+	for _, policy := range []string{``, `conflicts = "mvs"`, `conflicts = "highest"`} {
+		engine, project := conflictsTestEngine(t, policy+"\n"+`deps = ["example.com/o/g@1.0", "example.com/o/e@1"]`)
+		_, err := engine.Lock(project)
+		assert.NoError(t, err, policy)
+
+		lcc, err := engine.lockedConfigContentFromDir(project)
+		assert.NoError(t, err)
+		var nodes []string
+		for _, n := range lcc.Resolved {
+			nodes = append(nodes, n.String())
+		}
+		assert.ElementsMatch(t, []string{"example.com/o/g@2.0.0", "example.com/o/e@1.0.0"}, nodes, policy)
+
+		conflicts, err := engine.Conflicts(project)
+		assert.NoError(t, err)
+		if assert.Len(t, conflicts, 1, policy) {
+			assert.Equal(t, "2.0.0", conflicts[0].Selected, policy)
+			assert.Equal(t, "required at different major versions", conflicts[0].Warning, policy)
+		}
+	}
+
+	// fail
+	engine, project := conflictsTestEngine(t, `
+conflicts = "fail"
+deps = ["example.com/o/g@1.0", "example.com/o/e@1"]
+`)
+	_, err := engine.Lock(project)
+	assert.ErrorContains(t, err, "example.com/o/g: 2.0.0 (example.com/o/e@1.0.0), 1.0.0 (project)")
+
+	// settled by the overrides
+	engine, project = conflictsTestEngine(t, `
+overrides = { "example.com/o/g" = "2" }
+deps = ["example.com/o/g@1.0", "example.com/o/e@1"]
+`)
+	conflicts, err := engine.Conflicts(project)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, ConflictOverride, conflicts[0].Reason)
+	assert.Equal(t, "2.0.0", conflicts[0].Selected)
+	assert.Empty(t, conflicts[0].Warning)
+}
+
+func TestConflictsReadOnly(t *testing.T) {
+	engine, project := conflictsTestEngine(t, `deps = ["example.com/o/c@1.0", "example.com/o/a@1"]`)
+	lockFile := filepath.Join(project, ".bz.lock")
+
+	// without a lock file
+	conflicts, err := engine.Conflicts(project)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.NoFileExists(t, lockFile)
+	assert.NoDirExists(t, engine.lockMarkersDir())
+
+	// with a stale lock file
+	_, err = engine.Lock(project)
+	assert.NoError(t, err)
+	before, err := os.ReadFile(lockFile)
+	assert.NoError(t, err)
+	writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), `deps = ["example.com/o/c@1.2", "example.com/o/a@1"]`)
+	conflicts, err = engine.Conflicts(project)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+	after, err := os.ReadFile(lockFile)
+	assert.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+
+	// frozen
+	engine.appCtx.Frozen = true
+	_, err = engine.Conflicts(project)
+	assert.ErrorContains(t, err, "out of date (frozen mode)")
+}
+
+func TestConflictsReplace(t *testing.T) {
+	for _, replacement := range []string{"example.com/o/fork@1.2.0-patched", "../localc"} {
+		engine, project := conflictsTestEngine(t, fmt.Sprintf(`
+replace = { "example.com/o/c" = "%s" }
+deps = ["example.com/o/c@1.0", "example.com/o/a@1"]
+`, replacement))
+		_, err := engine.Lock(project)
+		assert.NoError(t, err, replacement)
+
+		// used everywhere and recorded in the lock file
+		lcc, err := engine.lockedConfigContentFromDir(project)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"example.com/o/c": replacement}, lcc.Replace)
+		var nodes []string
+		for _, n := range lcc.Resolved {
+			nodes = append(nodes, fmt.Sprintf("%s %v", n.String(), n.Parents))
+		}
+		assert.Len(t, nodes, 2, replacement)
+		assert.Equal(t, "example.com/o/a@1.0.0 []", nodes[0])
+		assert.Contains(t, nodes[1], "[example.com/o/a@1.0.0]")
+
+		conflicts, err := engine.Conflicts(project)
+		assert.NoError(t, err)
+		assert.Len(t, conflicts, 1)
+		assert.Equal(t, ConflictReplace, conflicts[0].Reason)
+		if replacement == "../localc" {
+			assert.Equal(t, filepath.Join(filepath.Dir(project), "localc"), lcc.Resolved[1].Repo)
+			assert.Equal(t, "local", lcc.Resolved[1].Server)
+		} else {
+			assert.Equal(t, replacement, conflicts[0].Replacement)
+		}
+	}
+}
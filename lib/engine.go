@@ -5,13 +5,14 @@ package lib
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/bazurto/bz/lib/model"
 	"github.com/bazurto/bz/lib/resolver"
@@ -92,21 +93,12 @@ func (o *Engine) ExecuteWithIO(
 	// Expand aliases
 	args = ctx.ResolveAlias(args)
 
-	// SetEnv
-	for k, v := range ctx.Env() {
+	// SetEnv, PATH is bin dirs followed by the original OS path
+	for k, v := range withOsPath(ctx.Env()) {
 		os.Setenv(k, v)
 	}
 
-	//originalOsPathParts := strings.Split(originalPathPathStr, string([]rune{os.PathListSeparator})) // ["/usr/local/bin", "/usr/bin"]
-	//ctx.SetPath(append(ctx.Path(), originalOsPathParts...))
 	// Restore OS path
-	os.Setenv(
-		"PATH",
-		strings.Join(
-			[]string{os.Getenv("PATH") + originalPathPathStr},
-			string([]rune{os.PathListSeparator}),
-		),
-	)
 	defer func() {
 		os.Setenv("PATH", originalPathPathStr)
 	}()
@@ -129,109 +121,131 @@ func (o *Engine) ExecuteWithIO(
 	return 0
 }
 
+// Environment returns the environment variables defined by rdep and all of its
+// dependencies.  PATH contains the bin dirs of the dependencies followed by the
+// current PATH
+func (o *Engine) Environment(rdep *model.ResolvedDependency) map[string]string {
+	return withOsPath(o.DependencyEnvironment(rdep))
+}
+
+// DependencyEnvironment works like Environment but PATH only contains the bin
+// dirs of the dependencies
+func (o *Engine) DependencyEnvironment(rdep *model.ResolvedDependency) map[string]string {
+	os.Setenv("BZ_PROJECT_DIR", rdep.Dir) // also have to be set in lib/model/resolveddependency.go
+	return rdep.Resolve().Env()
+}
+
+// withOsPath appends the current OS PATH to the PATH in env
+func withOsPath(env map[string]string) map[string]string {
+	var parts []string
+	if p := env["PATH"]; p != "" {
+		parts = append(parts, p)
+	}
+	if p := os.Getenv("PATH"); p != "" {
+		parts = append(parts, p)
+	}
+	env["PATH"] = strings.Join(parts, string([]rune{os.PathListSeparator}))
+	return env
+}
+
 func (o *Engine) AddResolver(r resolver.Resolver) {
 	Debug.Printf("Start AddResolver(%s)", r)
 	o.resolvers = append(o.resolvers, r)
 }
 
 func (o *Engine) ContextFromConfigDir(dir string) (*model.ResolvedDependency, error) {
+	rd, _, err := o.contextFromConfigDir(dir, true)
+	return rd, err
+}
+
+// contextFromConfigDir works like ContextFromConfigDir and also returns the
+// dependency conflicts settled while resolving the graph.  With fromResolved
+// the graph of a fresh lock file is read from its resolved section, no
+// conflicts are returned in that case
+func (o *Engine) contextFromConfigDir(dir string, fromResolved bool) (*model.ResolvedDependency, []*DependencyConflict, error) {
 	var err error
-	// Fuzzy Config Info
-	var fuzzyConfigModTime time.Time
-	fuzzyConfigFileName, fuzzyConfigFound := o.findFuzzyConfigFile(dir)
-	if fuzzyConfigFound {
-		if stat, err := os.Stat(fuzzyConfigFileName); err == nil {
-			fuzzyConfigModTime = stat.ModTime()
-		}
-	}
-
-	// Lock Config Info
-	var lockConfigModTime time.Time
-	lockConfigFileName := filepath.Join(dir, o.appCtx.LockFileName)
-	lockConfigStat, err := os.Stat(lockConfigFileName)
-	var lockConfigFound bool = true
-	if os.IsNotExist(err) {
-		lockConfigFound = false
-	} else {
-		lockConfigModTime = lockConfigStat.ModTime()
-	}
 
 	//
 	// Read
 	//
-	// if lockFileDoesNotExixts : read from fuzzy file
-	// if fuzzyFile newer       : read from fuzzy file
-	// if error                 : read from fuzzy file
-	// else						: read from lock file
-	readFuzzy := false
-	if !lockConfigFound {
-		// read fuzzy
-		readFuzzy = true
-		Debug.Print("lock file not found, setting readFuzzy flag to true")
-	} else if fuzzyConfigModTime.After(lockConfigModTime) {
-		// read fuzzy
-		readFuzzy = true
-		Debug.Print("fuzzy config file is newer thatn lock file, stting readFuzzy flag to true")
-	} else {
-		// read lock
-		Debug.Print("will read from lock file")
+	// if lockFileDoesNotExixts      : read from fuzzy file
+	// if error                      : read from fuzzy file
+	// if fuzzy config hash changed  : read from fuzzy file
+	// else                          : read from lock file
+	//
+	// In frozen mode the lock file has to be up to date
+	status, err := o.lockStatus(dir)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var shouldUpdateLockFile bool = false
-	var lcc *model.LockedConfigContent
-	if readFuzzy {
+	lcc := status.Lock
+	if status.Stale() {
+		if o.appCtx.Frozen {
+			return nil, nil, status.frozenError()
+		}
+		if status.LockErr != nil && !errors.Is(status.LockErr, utils.FileNotFoundError) {
+			Warn.Printf("Failed reading %s, updating with %s: %s", status.LockFile, status.ConfigFile, status.LockErr)
+		}
+		Debug.Printf("lock file is stale, reading %s", status.ConfigFile)
+
 		// read from .bz, .bz.hcl, .bz.json
 		lcc, err = o.readFuzzyConfigContentFromDir(dir)
 		shouldUpdateLockFile = true
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	} else {
-		// read lock file from .bz.lock
-		lcc, err = o.lockedConfigContentFromDir(dir)
-		if err != nil {
-			// on error ready fuzzy file
-			lcc, err = o.readFuzzyConfigContentFromDir(dir)
-			Warn.Printf("Failed reading %s, updating with %s", lockConfigFileName, fuzzyConfigFileName)
+		Debug.Print("will read from lock file")
+		if lcc.ConfigHash == "" && status.Config != nil && !o.appCtx.Frozen {
+			// written by an older bz: record the hash
+			lcc.ConfigHash = status.Config.Hash()
 			shouldUpdateLockFile = true
-			if err != nil {
-				return nil, err
-			}
 		}
 	}
 
 	//
 	Debug.Printf("read config: %v", lcc)
-	cdd := utils.NewCircularDependencyDetector()
-	v := model.NewVersion("0.0.0")
-	c := model.LockedCoord{
-		Server:  "localhost",
-		Owner:   "local",
-		Repo:    "local",
-		Version: v,
-	}
 
-	// resolve dependency
-	resolvedDependency, err := o.resolvedDependencyFromConfigContext(dir, &c, lcc, cdd)
-	if err != nil {
-		return nil, err
+	// resolve dependency.  The resolved section of a fresh lock file already
+	// has the whole graph with conflicts settled
+	var resolvedDependency *model.ResolvedDependency
+	var conflicts []*DependencyConflict
+	if fromResolved && !shouldUpdateLockFile {
+		resolvedDependency, err = o.resolvedDependencyFromLock(dir, lcc)
+		if err != nil {
+			Debug.Printf("resolving the graph of %s again: %s", status.LockFile, err)
+		}
+	}
+	if resolvedDependency == nil {
+		resolvedDependency, conflicts, err = o.resolveGraph(dir, lcc)
+		if err != nil {
+			return nil, conflicts, err
+		}
 	}
 
 	// update lock file
 	if shouldUpdateLockFile {
-		if e := o.updateLockFile(dir, resolvedDependency); e != nil {
+		if e := o.updateLockFile(dir, resolvedDependency, lcc); e != nil {
 			Warn.Println(e)
 		}
 	}
+	o.registerLockFile(dir)
 
-	return resolvedDependency, nil
+	return resolvedDependency, conflicts, nil
 }
 
+// resolvedDependencyFromConfigContext downloads the dependencies of bzContent
+// recursively.  Dependencies selected in g replace the versions required and
+// transitive dependencies found in g.pins, the resolved section of the root
+// lock file, are verified against the assets recorded there
 func (o *Engine) resolvedDependencyFromConfigContext(
 	dir string,
 	rcoord *model.LockedCoord,
 	bzContent *model.LockedConfigContent,
 	cdd *utils.CircularDependencyDetector,
+	g *graphResolution,
 ) (*model.ResolvedDependency, error) {
 	Debug.Printf("Start resolvedDependencyFromConfigContext(%s,%v)", dir, rcoord)
 
@@ -254,7 +268,8 @@ func (o *Engine) resolvedDependencyFromConfigContext(
 	triggers := bzContent.Triggers
 
 	var subDeps []*model.ResolvedDependency
-	for _, subLockedCoord := range bzContent.Deps {
+	for _, required := range bzContent.Deps {
+		subLockedCoord := g.require(rcoord, required)
 		cdd2 := cdd.Clone()
 		// Circular depedency protection
 		if err := cdd2.Push(subLockedCoord.CanonicalNameNoVersion()); err != nil {
@@ -275,8 +290,9 @@ func (o *Engine) resolvedDependencyFromConfigContext(
 		if err != nil {
 			return nil, fmt.Errorf("load sub dependency error: %w", err)
 		}
+		pinLockedCoords(subCc.Deps, g.pins)
 
-		subRd, err := o.resolvedDependencyFromConfigContext(extractToDir, subLockedCoord, subCc, cdd2.Clone())
+		subRd, err := o.resolvedDependencyFromConfigContext(extractToDir, subLockedCoord, subCc, cdd2.Clone(), g)
 		if err != nil {
 			return nil, fmt.Errorf("resole sub dependency error: : %w", err)
 		}
@@ -296,6 +312,114 @@ func (o *Engine) resolvedDependencyFromConfigContext(
 	return &rd, nil
 }
 
+// resolvedDependencyFromLock builds the dependency graph of lcc, the fresh
+// lock of the project in dir, from its resolved section: every dependency uses
+// the version recorded there and its parents say where it is required.  The
+// lock files of the dependencies are only read for their env, aliases, bin dir
+// and the order of their deps.  It fails when the resolved section does not
+// match the deps of a dependency, e.g. lock files written by an older bz
+func (o *Engine) resolvedDependencyFromLock(dir string, lcc *model.LockedConfigContent) (*model.ResolvedDependency, error) {
+	if len(lcc.Resolved) == 0 && len(lcc.Deps) > 0 {
+		return nil, fmt.Errorf("no resolved section")
+	}
+
+	// parent => nodes required by it, "" is the project
+	children := make(map[string][]*model.LockedNode)
+	for _, n := range lcc.Resolved {
+		if n.Direct {
+			children[""] = append(children[""], n)
+		}
+		for _, parent := range n.Parents {
+			children[parent] = append(children[parent], n)
+		}
+	}
+
+	// name => name of the dependency used instead
+	g := newGraphResolution(lockedNodesByName(lcc.Resolved))
+	replacedBy := make(map[string]string)
+	for name, replacement := range lcc.Replace {
+		lc, err := o.resolveReplacement(g, dir, replacement)
+		if err != nil {
+			return nil, fmt.Errorf("replace `%s`: %w", name, err)
+		}
+		name, _, _ = strings.Cut(name, "@")
+		replacedBy[name] = lc.CanonicalNameNoVersion()
+	}
+
+	var build func(
+		dir, key string,
+		rcoord *model.LockedCoord,
+		content *model.LockedConfigContent,
+		cdd *utils.CircularDependencyDetector,
+	) (*model.ResolvedDependency, error)
+	build = func(
+		dir, key string,
+		rcoord *model.LockedCoord,
+		content *model.LockedConfigContent,
+		cdd *utils.CircularDependencyDetector,
+	) (*model.ResolvedDependency, error) {
+		if len(content.Deps) != len(children[key]) {
+			return nil, fmt.Errorf("%s: %d deps, %d in the resolved section", rcoord, len(content.Deps), len(children[key]))
+		}
+
+		var subDeps []*model.ResolvedDependency
+		for _, required := range content.Deps {
+			name := required.CanonicalNameNoVersion()
+			if replacement, ok := replacedBy[name]; ok {
+				name = replacement
+			}
+			var node *model.LockedNode
+			for _, n := range children[key] {
+				if n.CanonicalNameNoVersion() == name {
+					node = n
+					break
+				}
+			}
+			if node == nil {
+				return nil, fmt.Errorf("%s: %s is not in the resolved section", rcoord, required)
+			}
+
+			cdd2 := cdd.Clone()
+			if err := cdd2.Push(node.CanonicalNameNoVersion()); err != nil {
+				return nil, fmt.Errorf("resolvedDependencyFromLock: %w", err)
+			}
+
+			lc := node.LockedCoord
+			extractToDir, err := o.downloadAndInstallDependencyIfNotExists(&lc)
+			if err != nil {
+				return nil, err
+			}
+			subCc, err := o.lockedConfigContentFromDir(extractToDir)
+			if err != nil {
+				return nil, fmt.Errorf("load sub dependency error: %w", err)
+			}
+			subRd, err := build(extractToDir, node.String(), &lc, subCc, cdd2)
+			if err != nil {
+				return nil, err
+			}
+			subDeps = append(subDeps, subRd)
+		}
+
+		rd := &model.ResolvedDependency{
+			Coord:    *rcoord,
+			Dir:      utils.FsAbs(dir),
+			BinDir:   content.BinDir,
+			Exports:  content.Export,
+			Alias:    content.Alias,
+			Triggers: content.Triggers,
+			Sub:      subDeps,
+		}
+		if rd.Exports == nil {
+			rd.Exports = make(map[string]string)
+		}
+		if rd.Alias == nil {
+			rd.Alias = make(map[string]string)
+		}
+		return rd, nil
+	}
+	return build(dir, "", rootCoord(), lcc, utils.NewCircularDependencyDetector())
+}
+
 /*
 func (o *Engine) resolvedCoordToDir(rcoord *model.LockedCoord) string {
 	dir := filepath.Join(
@@ -337,23 +461,51 @@ func (o *Engine) findFuzzyConfigFile(dir string) (string, bool) {
 	return "", false
 }
 
+// fuzzyConfigContentFromDir reads the configuration file (.bz.hcl, .bz.json, .bz) found
+// in dir.  It returns false if there is no configuration file
+func (o *Engine) fuzzyConfigContentFromDir(dir string) (*model.FuzzyConfigContent, bool, error) {
+	configFile, found := o.findFuzzyConfigFile(dir)
+	if !found {
+		return nil, false, nil
+	}
+
+	cc, err := model.FuzzyConfigContentFromFile(configFile)
+	if err != nil {
+		return nil, true, fmt.Errorf("error reading %s: %w", configFile, err)
+	}
+	return cc, true, nil
+}
+
+// newFuzzyCoord parses dep, a dependency of cc.  Pre-releases are allowed
+// when cc sets allowPrerelease or when prerelease is true (--prerelease)
+func newFuzzyCoord(dep string, cc *model.FuzzyConfigContent, prerelease bool) (*model.FuzzyCoord, error) {
+	fc, err := model.NewCoordFromStr(dep)
+	if err != nil {
+		return nil, err
+	}
+	fc.Prerelease = prerelease || (cc != nil && cc.AllowPrerelease)
+	return fc, nil
+}
+
 // readFuzzyConfigContentFromDir takes a directory name `dir` and returns the json or hcl from the
 // configuration file as a struct.
 func (o *Engine) readFuzzyConfigContentFromDir(extractToDir string) (*model.LockedConfigContent, error) {
-	var cc *model.FuzzyConfigContent
-	var err error
+	return o.readFuzzyConfigContentFromDirKeeping(extractToDir, nil)
+}
 
-	// if file is in directory, return configuration
-	// otherwise, return default
-	if configFile, found := o.findFuzzyConfigFile(extractToDir); found {
-		cc, err = model.FuzzyConfigContentFromFile(configFile)
-		if err != nil {
-			return nil, fmt.Errorf("error reading %s: %w", configFile, err)
-		}
+// readFuzzyConfigContentFromDirKeeping works like readFuzzyConfigContentFromDir but it does not
+// resolve the dependencies for which keep returns a locked coord.  keep may be nil
+func (o *Engine) readFuzzyConfigContentFromDirKeeping(
+	extractToDir string,
+	keep func(fc *model.FuzzyCoord) *model.LockedCoord,
+) (*model.LockedConfigContent, error) {
+	cc, found, err := o.fuzzyConfigContentFromDir(extractToDir)
+	if err != nil {
+		return nil, err
 	}
 
 	// empty
-	if cc == nil {
+	if !found {
 		cc = &model.FuzzyConfigContent{
 			BinDir: filepath.Join(extractToDir, "bin"),
 			Deps:   nil,
@@ -364,7 +516,7 @@ func (o *Engine) readFuzzyConfigContentFromDir(extractToDir string) (*model.Lock
 
 	var lockedCoords []*model.LockedCoord
 	for _, dep := range cc.Deps {
-		fuzzyCoord, err := model.NewCoordFromStr(dep)
+		fuzzyCoord, err := newFuzzyCoord(dep, cc, o.appCtx.Prerelease)
 		if err != nil {
 			return nil, err
 
@@ -372,17 +524,14 @@ func (o *Engine) readFuzzyConfigContentFromDir(extractToDir string) (*model.Lock
 
 		//
 		var lockCoord *model.LockedCoord
-		for _, resolver := range o.resolvers {
-			lockCoord, err = resolver.ResolveCoord(fuzzyCoord)
-			if err != nil {
-				return nil, fmt.Errorf("resolvedDependencyFromConfigContext: ResolveCoord: %w", err)
-			}
-			if lockCoord != nil {
-				break
-			}
+		if keep != nil {
+			lockCoord = keep(fuzzyCoord)
 		}
 		if lockCoord == nil {
-			return nil, fmt.Errorf("resolvedDependencyFromConfigContext: unable to resolve `%s`", lockCoord)
+			lockCoord, err = o.resolveCoord(fuzzyCoord)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		lockedCoords = append(lockedCoords, lockCoord)
@@ -391,6 +540,9 @@ func (o *Engine) readFuzzyConfigContentFromDir(extractToDir string) (*model.Lock
 
 	// return locked config content
 	lcc := model.LockedConfigContent{}
+	if found {
+		lcc.ConfigHash = cc.Hash()
+	}
 	lcc.BinDir = cc.BinDir
 	lcc.Alias = cc.Alias
 	lcc.Export = cc.Export
@@ -398,20 +550,66 @@ func (o *Engine) readFuzzyConfigContentFromDir(extractToDir string) (*model.Lock
 		lcc.Triggers = *cc.Triggers
 	}
 	lcc.Deps = lockedCoords
+	lcc.Conflicts = cc.Conflicts
+	lcc.Overrides = cc.Overrides
+	lcc.Replace = cc.Replace
+
+	// keep the target platforms and the digests of the previous lock file
+	if old, err := o.lockedConfigContentFromDir(extractToDir); err == nil {
+		lcc.Platforms = old.Platforms
+		lcc.Resolved = old.Resolved
+	}
 
 	return &lcc, nil
 }
 
-func (o *Engine) updateLockFile(dir string, rd *model.ResolvedDependency) error {
+// resolveCoord asks every resolver to resolve fuzzyCoord.  The first
+// resolver that knows about the coordinate wins
+func (o *Engine) resolveCoord(fuzzyCoord *model.FuzzyCoord) (*model.LockedCoord, error) {
+	for _, resolver := range o.resolvers {
+		lockCoord, err := resolver.ResolveCoord(fuzzyCoord)
+		if err != nil {
+			return nil, fmt.Errorf("resolvedDependencyFromConfigContext: ResolveCoord: %w", err)
+		}
+		if lockCoord != nil {
+			return lockCoord, nil
+		}
+	}
+	return nil, fmt.Errorf("resolvedDependencyFromConfigContext: unable to resolve `%s`", fuzzyCoord.OriginalString)
+}
+
+func (o *Engine) updateLockFile(dir string, rd *model.ResolvedDependency, lcc *model.LockedConfigContent) error {
 	lockFileName := filepath.Join(dir, o.appCtx.LockFileName)
 
 	cc := model.LockedConfigContent{}
+	cc.ConfigHash = lcc.ConfigHash
 	cc.Alias = rd.Alias
 	cc.Triggers = rd.Triggers
 	cc.Export = rd.Exports
 	cc.BinDir = rd.BinDir
-	for _, r := range rd.Sub {
-		cc.Deps = append(cc.Deps, &r.Coord)
+	cc.Platforms = lcc.Platforms
+	cc.Conflicts = lcc.Conflicts
+	cc.Overrides = lcc.Overrides
+	cc.Replace = lcc.Replace
+	cc.Deps = lcc.Deps // versions required by the configuration, conflicts are settled in Resolved
+	if err := o.lockPlatforms(cc.Deps, cc.Platforms); err != nil {
+		return err
+	}
+	cc.Resolved = resolvedNodes(rd)
+	direct := make(map[string]*model.LockedCoord)
+	for _, lc := range cc.Deps {
+		direct[lc.String()] = lc
+	}
+	var others []*model.LockedCoord // transitive or selected instead of a direct dependency
+	for _, n := range cc.Resolved {
+		if lc, ok := direct[n.String()]; ok {
+			n.Platforms = lc.Platforms // already locked
+		} else {
+			others = append(others, &n.LockedCoord)
+		}
+	}
+	if err := o.lockPlatforms(others, cc.Platforms); err != nil {
+		return err
 	}
 
 	f, err := os.Create(lockFileName)
@@ -426,6 +624,104 @@ func (o *Engine) updateLockFile(dir string, rd *model.ResolvedDependency) error
 	return enc.Encode(cc)
 }
 
+// resolvedNodes flattens the dependency graph of rd.  Nodes are sorted by
+// name and version
+func resolvedNodes(rd *model.ResolvedDependency) []*model.LockedNode {
+	nodes := make(map[string]*model.LockedNode)
+	var walk func(parent string, rd *model.ResolvedDependency)
+	walk = func(parent string, rd *model.ResolvedDependency) {
+		for _, sub := range rd.Sub {
+			key := sub.Coord.String()
+			n, ok := nodes[key]
+			if !ok {
+				n = &model.LockedNode{LockedCoord: sub.Coord}
+				nodes[key] = n
+				walk(key, sub)
+			}
+			if parent == "" {
+				n.Direct = true
+			} else if !containsString(n.Parents, parent) {
+				n.Parents = append(n.Parents, parent)
+				sort.Strings(n.Parents)
+			}
+		}
+	}
+	walk("", rd)
+
+	var keys []string
+	for k := range nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var res []*model.LockedNode
+	for _, k := range keys {
+		res = append(res, nodes[k])
+	}
+	return res
+}
+
+// lockedNodesByName returns LockedCoord.String() => node
+func lockedNodesByName(nodes []*model.LockedNode) map[string]*model.LockedNode {
+	res := make(map[string]*model.LockedNode)
+	for _, n := range nodes {
+		res[n.String()] = n
+	}
+	return res
+}
+
+// pinLockedCoords copies the assets recorded in pins to the coords in deps
+// that do not have them, so the download is verified against the root lock file
+func pinLockedCoords(deps []*model.LockedCoord, pins map[string]*model.LockedNode) {
+	for _, lc := range deps {
+		n, ok := pins[lc.String()]
+		if !ok {
+			continue
+		}
+		if lc.Asset == nil && n.Asset != nil {
+			asset := *n.Asset
+			lc.Asset = &asset
+		}
+		if lc.Platforms == nil {
+			lc.Platforms = n.Platforms
+		}
+	}
+}
+
+// lockPlatforms asks every resolver to lock the asset of every platform in
+// platforms (os-arch) for deps.  The first resolver that knows about a
+// dependency wins
+func (o *Engine) lockPlatforms(deps []*model.LockedCoord, platforms []string) error {
+	var targets []model.Platform
+	for _, p := range platforms {
+		platform, err := model.NewPlatformFromStr(p)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, platform)
+	}
+
+	for _, lc := range deps {
+		if len(targets) == 0 {
+			lc.Platforms = nil
+			continue
+		}
+		for _, r := range o.resolvers {
+			locker, ok := r.(resolver.PlatformLocker)
+			if !ok {
+				continue
+			}
+			handled, err := locker.LockPlatforms(lc, targets)
+			if err != nil {
+				return err
+			}
+			if handled {
+				break
+			}
+		}
+	}
+	return nil
+}
+
 // downloadAndInstallDependencyIfNotExists does the actual work of installing
 // the dependency.  It loops through all resolvers
 // and unzips the dependency
@@ -445,6 +741,7 @@ func (o *Engine) downloadAndInstallDependencyIfNotExists(lockCoord *model.Locked
 			break
 		}
 	}
+	o.touchCacheEntry(extractToDir)
 
 	/*
 		err = o.extractDependency(lockCoord, file, extractToDir)
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// InitOptions describes the project created by Init
+type InitOptions struct {
+	Format  string   // hcl or json
+	Deps    []string // server/owner/repo[@version]
+	Package bool     // add bin/ dir, alias, env and triggers samples
+	Force   bool     // overwrite an existing configuration file
+}
+
+// Init creates the configuration file in dir, resolves its dependencies and writes
+// the lock file.  It returns the configuration file created
+func (o *Engine) Init(dir string, opts InitOptions) (string, error) {
+	dir = utils.FsAbs(dir)
+	for _, dep := range opts.Deps {
+		if _, err := model.NewCoordFromStr(dep); err != nil {
+			return "", err
+		}
+	}
+
+	configFile, err := o.initConfigFileName(dir, opts.Format)
+	if err != nil {
+		return "", err
+	}
+	if existing, found := o.findFuzzyConfigFile(dir); found && !opts.Force {
+		return "", fmt.Errorf("%s already exists", existing)
+	}
+
+	name := filepath.Base(dir)
+	content := initConfigContent(name, opts)
+	var b []byte
+	if opts.Format == "json" {
+		b, err = initJson(content)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		b = initHcl(content)
+	}
+
+	if err := utils.MkdirIfNotExists(dir); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(configFile, b, 0644); err != nil {
+		return "", fmt.Errorf("Init(): %w", err)
+	}
+	if opts.Package {
+		if err := initPackageBinDir(dir, name); err != nil {
+			return configFile, err
+		}
+	}
+
+	if _, err := o.Lock(dir); err != nil {
+		return configFile, err
+	}
+	return configFile, nil
+}
+
+// initConfigFileName returns the configuration file name for format.  The
+// name comes from the configuration file names bz looks for
+func (o *Engine) initConfigFileName(dir, format string) (string, error) {
+	ext := ".hcl"
+	switch format {
+	case "", "hcl":
+	case "json":
+		ext = ".json"
+	default:
+		return "", fmt.Errorf("unknown format `%s`, expected hcl or json", format)
+	}
+	for _, name := range o.appCtx.ConfigFileNames {
+		if filepath.Ext(name) == ext {
+			return filepath.Join(dir, name), nil
+		}
+	}
+	return "", fmt.Errorf("no %s configuration file name in %v", format, o.appCtx.ConfigFileNames)
+}
+
+// initConfigContent returns the configuration of a new project named name
+func initConfigContent(name string, opts InitOptions) *model.FuzzyConfigContent {
+	cc := &model.FuzzyConfigContent{Deps: opts.Deps}
+	if cc.Deps == nil {
+		cc.Deps = []string{}
+	}
+	if opts.Package {
+		cc.Alias = map[string]string{
+			fmt.Sprintf("hello-%s", name): fmt.Sprintf("$BINDIR/%s", name),
+		}
+		cc.Export = map[string]string{
+			fmt.Sprintf("%s_HOME", utils.ToEnvKey(name)): "$DIR",
+		}
+		cc.Triggers = &model.Triggers{}
+	}
+	return cc
+}
+
+// initHcl formats cc as .bz.hcl.  It is written by hand instead of with
+// hclwrite to keep one dependency per line and comments for the package sections
+func initHcl(cc *model.FuzzyConfigContent) []byte {
+	w := bytes.NewBuffer(nil)
+	fmt.Fprintf(w, "# dependencies: server/owner/repo@version\n")
+	if len(cc.Deps) == 0 {
+		fmt.Fprintf(w, "deps = []\n")
+	} else {
+		fmt.Fprintf(w, "deps = [\n")
+		for _, dep := range cc.Deps {
+			fmt.Fprintf(w, "  %s,\n", utils.HclQuote(dep))
+		}
+		fmt.Fprintf(w, "]\n")
+	}
+
+	if cc.Export != nil {
+		fmt.Fprintf(w, "\n# variables available to this project and every project depending on it\n")
+		hclMap(w, "env", cc.Export)
+	}
+	if cc.Alias != nil {
+		fmt.Fprintf(w, "\n# commands executed with `bz <alias> [args...]`.  Aliases are portable between operating systems\n")
+		hclMap(w, "alias", cc.Alias)
+	}
+	if cc.Triggers != nil {
+		fmt.Fprintf(w, "\ntriggers {\n")
+		fmt.Fprintf(w, "  # JavaScript executed after the package is downloaded\n")
+		fmt.Fprintf(w, "  # installScript = \"std.printf('installed')\"\n")
+		fmt.Fprintf(w, "}\n")
+	}
+	return w.Bytes()
+}
+
+func hclMap(w *bytes.Buffer, name string, m map[string]string) {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "%s = {\n", name)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s = %s\n", utils.HclQuote(k), utils.HclQuote(m[k]))
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// initJson formats cc as .bz.json
+func initJson(cc *model.FuzzyConfigContent) ([]byte, error) {
+	content := struct {
+		Deps     []string          `json:"deps"`
+		Export   map[string]string `json:"env,omitempty"`
+		Alias    map[string]string `json:"alias,omitempty"`
+		Triggers *model.Triggers   `json:"triggers,omitempty"`
+	}{cc.Deps, cc.Export, cc.Alias, cc.Triggers}
+
+	b, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("initJson(): %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+// initPackageBinDir creates bin/ with a sample executable
+func initPackageBinDir(dir, name string) error {
+	binDir := filepath.Join(dir, "bin")
+	if err := utils.MkdirIfNotExists(binDir); err != nil {
+		return err
+	}
+	script := filepath.Join(binDir, name)
+	if utils.FileExists(script) {
+		return nil
+	}
+	content := fmt.Sprintf("#!/bin/sh\n# executables in bin/ are added to PATH of projects depending on %s\necho \"Hello from %s $*\"\n", name, name)
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		return fmt.Errorf("initPackageBinDir(): %w", err)
+	}
+	return nil
+}
+
+// ResolveCoord resolves dep to the version that would be locked
+func (o *Engine) ResolveCoord(dep string) (*model.LockedCoord, error) {
+	fc, err := model.NewCoordFromStr(dep)
+	if err != nil {
+		return nil, err
+	}
+	return o.resolveCoord(fc)
+}
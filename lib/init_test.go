@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit(t *testing.T) {
+	appCtx := model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(t.TempDir(), "cache"),
+		ConfigFileNames:  []string{".bz.hcl", ".bz.json", ".bz"},
+	}
+
+	for _, format := range []string{"hcl", "json"} {
+		dir := filepath.Join(t.TempDir(), "my-tool")
+		configFile, err := NewEngine(appCtx).Init(dir, InitOptions{Format: format, Package: true})
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, ".bz."+format), configFile)
+		assert.FileExists(t, filepath.Join(dir, "bin", "my-tool"))
+		assert.FileExists(t, filepath.Join(dir, ".bz.lock"))
+
+		cc, err := model.FuzzyConfigContentFromFile(configFile)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"hello-my-tool": "$BINDIR/my-tool"}, cc.Alias)
+		assert.Equal(t, map[string]string{"MY_TOOL_HOME": "$DIR"}, cc.Export)
+		assert.NotNil(t, cc.Triggers)
+
+		_, err = NewEngine(appCtx).Init(dir, InitOptions{Format: format})
+		assert.ErrorContains(t, err, "already exists")
+	}
+}
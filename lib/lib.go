@@ -10,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/bazurto/bz/lib/resolver"
 )
 
 var (
@@ -31,6 +33,15 @@ func init() {
 	Info = log.New(os.Stderr, "[I]", log.LstdFlags)
 }
 
+// EnableDebug turns on debug logging regardless of the DEBUG env var
+func EnableDebug() {
+	for _, l := range []*log.Logger{Debug, resolver.Debug} {
+		l.SetOutput(os.Stderr)
+		l.SetPrefix("[D]")
+		l.SetFlags(log.LstdFlags)
+	}
+}
+
 // PathFound struct returned by FindFileUpwards
 type PathFound struct {
 	Root string
@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+)
+
+// CoordChange describes how a dependency changed in the lock file
+type CoordChange struct {
+	Name string             // server/owner/repo
+	Old  *model.LockedCoord // nil when the dependency was added
+	New  *model.LockedCoord // nil when the dependency was removed
+}
+
+func (c CoordChange) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s", c.New)
+	case c.New == nil:
+		return fmt.Sprintf("- %s", c.Old)
+	}
+	return fmt.Sprintf("~ %s %s -> %s", c.Name, c.Old.Version.Canonical(), c.New.Version.Canonical())
+}
+
+// Lock re-resolves every dependency in the configuration found in dir,
+// downloads what is needed and rewrites the lock file.  It returns the
+// dependencies that changed compared to the previous lock file.
+func (o *Engine) Lock(dir string) ([]CoordChange, error) {
+	return o.LockForPlatforms(dir, nil)
+}
+
+// LockForPlatforms works like Lock and also records in the lock file the asset
+// of every dependency for each platform (os/arch) without downloading them.
+// When platforms is nil the platforms of the previous lock file are kept
+func (o *Engine) LockForPlatforms(dir string, platforms []string) ([]CoordChange, error) {
+	var targets []string
+	for _, p := range platforms {
+		platform, err := model.NewPlatformFromStr(p)
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(targets, platform.String()) {
+			targets = append(targets, platform.String())
+		}
+	}
+
+	// frozen: only check the lock file
+	if o.appCtx.Frozen {
+		status, err := o.lockStatus(dir)
+		if err != nil {
+			return nil, err
+		}
+		if status.Stale() {
+			return nil, status.frozenError()
+		}
+		for _, p := range targets {
+			if !containsString(status.Lock.Platforms, p) {
+				return nil, fmt.Errorf("%s does not lock platform %s (frozen mode)", status.LockFile, p)
+			}
+		}
+		return nil, nil
+	}
+
+	// previous lock, if any
+	var oldDeps []*model.LockedCoord
+	if old, err := o.lockedConfigContentFromDir(dir); err == nil {
+		oldDeps = old.Deps
+	} else {
+		Debug.Printf("Lock(): no previous lock file: %s", err)
+	}
+
+	lcc, err := o.readFuzzyConfigContentFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if platforms != nil {
+		lcc.Platforms = targets
+	}
+
+	if err := o.writeLock(dir, lcc); err != nil {
+		return nil, err
+	}
+
+	return diffLockedCoords(oldDeps, lcc.Deps), nil
+}
+
+// writeLock downloads every dependency in lcc and writes the lock file
+func (o *Engine) writeLock(dir string, lcc *model.LockedConfigContent) error {
+	resolvedDependency, _, err := o.resolveGraph(dir, lcc)
+	if err != nil {
+		return err
+	}
+
+	if err := o.updateLockFile(dir, resolvedDependency, lcc); err != nil {
+		return fmt.Errorf("writing %s: %w", filepath.Join(dir, o.appCtx.LockFileName), err)
+	}
+	o.registerLockFile(dir)
+	return nil
+}
+
+// diffLockedCoords returns the changes needed to go from oldDeps to newDeps
+func diffLockedCoords(oldDeps, newDeps []*model.LockedCoord) []CoordChange {
+	var changes []CoordChange
+	oldByName := make(map[string]*model.LockedCoord)
+	for _, c := range oldDeps {
+		oldByName[c.CanonicalNameNoVersion()] = c
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range newDeps {
+		name := c.CanonicalNameNoVersion()
+		seen[name] = true
+		old, ok := oldByName[name]
+		if !ok {
+			changes = append(changes, CoordChange{Name: name, New: c})
+		} else if old.Version.Canonical() != c.Version.Canonical() {
+			changes = append(changes, CoordChange{Name: name, Old: old, New: c})
+		}
+	}
+
+	for _, c := range oldDeps {
+		name := c.CanonicalNameNoVersion()
+		if !seen[name] {
+			changes = append(changes, CoordChange{Name: name, Old: c})
+		}
+	}
+	return changes
+}
+
+// rootCoord is the coordinate given to the project itself
+func rootCoord() *model.LockedCoord {
+	return &model.LockedCoord{
+		Server:  "localhost",
+		Owner:   "local",
+		Repo:    "local",
+		Version: model.NewVersion("0.0.0"),
+	}
+}
+
+// LockStatus describes whether the lock file in a directory is up to
+// date with the configuration file
+type LockStatus struct {
+	ConfigFile string                     // configuration file, empty if not found
+	LockFile   string                     // lock file
+	Config     *model.FuzzyConfigContent  // nil if there is no configuration file
+	Lock       *model.LockedConfigContent // nil if the lock file is missing or unreadable
+	LockErr    error                      // reason why Lock is nil
+	Prerelease bool                       // pre-releases are allowed for every dependency
+}
+
+// Stale returns true when the lock file has to be recreated from the
+// configuration file
+func (s *LockStatus) Stale() bool {
+	if s.Lock == nil {
+		return true
+	}
+	if s.Config == nil {
+		return false // no configuration, lock file is all we have
+	}
+	if s.Lock.ConfigHash == "" {
+		// written by an older bz, the hash is added on the next write
+		return len(s.differences()) > 0
+	}
+	return s.Lock.ConfigHash != s.Config.Hash()
+}
+
+// Drift returns a human readable list of differences between the
+// configuration and the lock file.  It does not reach the network.
+func (s *LockStatus) Drift() []string {
+	if s.Lock == nil {
+		return []string{fmt.Sprintf("%s: %s", s.LockFile, s.LockErr)}
+	}
+	if s.Config == nil {
+		return nil
+	}
+	drift := s.differences()
+	if len(drift) == 0 && s.Lock.ConfigHash != "" && s.Lock.ConfigHash != s.Config.Hash() {
+		drift = append(drift, fmt.Sprintf("%s changed", filepath.Base(s.ConfigFile)))
+	}
+	return drift
+}
+
+// differences returns the differences between the configuration and the
+// lock file that are visible without the configuration hash
+func (s *LockStatus) differences() []string {
+	var drift []string
+
+	// deps
+	matched := make(map[*model.LockedCoord]bool)
+	for _, dep := range s.Config.Deps {
+		fc, err := newFuzzyCoord(dep, s.Config, s.Prerelease)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("! %s", err))
+			continue
+		}
+		var lc *model.LockedCoord
+		for _, c := range s.Lock.Deps {
+			if sameDependency(fc, c) {
+				lc = c
+				break
+			}
+		}
+		if lc == nil {
+			drift = append(drift, fmt.Sprintf("+ %s (not locked)", dep))
+			continue
+		}
+		matched[lc] = true
+		if !lockedCoordSatisfies(fc, lc) {
+			drift = append(drift, fmt.Sprintf("~ %s: locked version %s does not match `%s`", fc.CanonicalNameNoVersion(), lc.Version.Canonical(), fc.Version))
+		}
+	}
+	for _, c := range s.Lock.Deps {
+		if !matched[c] {
+			drift = append(drift, fmt.Sprintf("- %s (not in %s)", c, filepath.Base(s.ConfigFile)))
+		}
+	}
+
+	// everything else
+	if s.Config.BinDir != s.Lock.BinDir {
+		drift = append(drift, fmt.Sprintf("~ binDir: `%s` -> `%s`", s.Lock.BinDir, s.Config.BinDir))
+	}
+	if !sameStringMap(s.Config.Export, s.Lock.Export) {
+		drift = append(drift, "~ env changed")
+	}
+	if !sameStringMap(s.Config.Alias, s.Lock.Alias) {
+		drift = append(drift, "~ alias changed")
+	}
+	var triggers model.Triggers
+	if s.Config.Triggers != nil {
+		triggers = *s.Config.Triggers
+	}
+	if triggers.InstallScript != s.Lock.Triggers.InstallScript || triggers.PreRunScript != s.Lock.Triggers.PreRunScript {
+		drift = append(drift, "~ triggers changed")
+	}
+	if s.Config.Conflicts != s.Lock.Conflicts {
+		drift = append(drift, fmt.Sprintf("~ conflicts: `%s` -> `%s`", s.Lock.Conflicts, s.Config.Conflicts))
+	}
+	if !sameStringMap(s.Config.Overrides, s.Lock.Overrides) {
+		drift = append(drift, "~ overrides changed")
+	}
+	if !sameStringMap(s.Config.Replace, s.Lock.Replace) {
+		drift = append(drift, "~ replace changed")
+	}
+	return drift
+}
+
+func (s *LockStatus) frozenError() error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is out of date (frozen mode):\n", s.LockFile)
+	for _, d := range s.Drift() {
+		fmt.Fprintf(&b, "  %s\n", d)
+	}
+	fmt.Fprintf(&b, "run `bz :lock` to update it")
+	return fmt.Errorf("%s", b.String())
+}
+
+// lockStatus reads the configuration and the lock file from dir
+func (o *Engine) lockStatus(dir string) (*LockStatus, error) {
+	status := &LockStatus{
+		LockFile:   filepath.Join(dir, o.appCtx.LockFileName),
+		Prerelease: o.appCtx.Prerelease,
+	}
+
+	cc, found, err := o.fuzzyConfigContentFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		status.ConfigFile, _ = o.findFuzzyConfigFile(dir)
+		status.Config = cc
+	}
+
+	status.Lock, status.LockErr = o.lockedConfigContentFromDir(dir)
+	return status, nil
+}
+
+// sameDependency returns true if lc is the locked version of fc.
+func sameDependency(fc *model.FuzzyCoord, lc *model.LockedCoord) bool {
+	if fc.CanonicalNameNoVersion() == lc.CanonicalNameNoVersion() {
+		return true
+	}
+	// local dependencies keep the path in Repo: local/path/to/dir => {Server: local, Repo: /path/to/dir}
+	return (fc.Server == "local" || fc.Server == "local.local") &&
+		fc.Server == lc.Server &&
+		fc.OriginalString == lc.Server+lc.Repo
+}
+
+// lockedCoordSatisfies returns true if the version of lc satisfies the
+// version requested by fc
+func lockedCoordSatisfies(fc *model.FuzzyCoord, lc *model.LockedCoord) bool {
+	if fc.Version == "" || fc.Version == "0" {
+		return true // latest
+	}
+	if lc.Server == "local" || lc.Server == "local.local" {
+		return true // local dependencies are not versioned
+	}
+	return fc.Constraint().Matches(lc.Version)
+}
+
+// sameStringMap compares maps treating nil and empty as equal
+func sameStringMap(a, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockStatusStale(t *testing.T) {
+	cc := &model.FuzzyConfigContent{Deps: []string{"github.com/bazurto/python@3"}}
+	status := LockStatus{
+		Config: cc,
+		Lock: &model.LockedConfigContent{
+			ConfigHash: cc.Hash(),
+			Deps: []*model.LockedCoord{
+				{Server: "github.com", Owner: "bazurto", Repo: "python", Version: model.NewVersion("3.11.1")},
+			},
+		},
+	}
+	assert.False(t, status.Stale())
+
+	cc.Deps = append(cc.Deps, "github.com/bazurto/groovy@4")
+	assert.True(t, status.Stale())
+	assert.Equal(t, []string{"+ github.com/bazurto/groovy@4 (not locked)"}, status.Drift())
+}
+
+func TestLockStatusDrift(t *testing.T) {
+	status := LockStatus{
+		ConfigFile: "/project/.bz.hcl",
+		Config:     &model.FuzzyConfigContent{Deps: []string{"github.com/bazurto/python@3"}},
+		Lock: &model.LockedConfigContent{
+			Deps: []*model.LockedCoord{
+				{Server: "github.com", Owner: "bazurto", Repo: "python", Version: model.NewVersion("2.7.18")},
+				{Server: "github.com", Owner: "bazurto", Repo: "groovy", Version: model.NewVersion("4.0.11")},
+			},
+			Export: map[string]string{"A": "B"},
+		},
+	}
+	assert.True(t, status.Stale())
+	assert.Equal(t, []string{
+		"~ github.com/bazurto/python: locked version 2.7.18 does not match `3`",
+		"- github.com/bazurto/groovy@4.0.11 (not in .bz.hcl)",
+		"~ env changed",
+	}, status.Drift())
+}
+
+func TestLockStatusDriftPrerelease(t *testing.T) {
+	cc := &model.FuzzyConfigContent{Deps: []string{"github.com/bazurto/python@3"}}
+	status := LockStatus{
+		ConfigFile: "/project/.bz.hcl",
+		Config:     cc,
+		Lock: &model.LockedConfigContent{
+			ConfigHash: "sha256:old",
+			Deps: []*model.LockedCoord{
+				{Server: "github.com", Owner: "bazurto", Repo: "python", Version: model.NewVersion("3.12.0-rc1")},
+			},
+		},
+	}
+	assert.Equal(t, []string{"~ github.com/bazurto/python: locked version 3.12.0-rc1 does not match `3`"}, status.Drift())
+
+	// allowed: only the configuration changed
+	cc.AllowPrerelease = true
+	assert.Equal(t, []string{".bz.hcl changed"}, status.Drift())
+
+	cc.AllowPrerelease = false
+	status.Prerelease = true
+	assert.Equal(t, []string{".bz.hcl changed"}, status.Drift())
+}
+
+// platformResolver resolves example.com dependencies to version 1.0.0 of
+// the package in dirs[repo]
+type platformResolver struct {
+	dirs       map[string]string
+	locked     [][]model.Platform
+	downloaded map[string]*model.LockedAsset // asset of every download
+}
+
+func (r *platformResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord, error) {
+	return &model.LockedCoord{Server: c.Server, Owner: c.Owner, Repo: c.Repo, Version: model.NewVersion("1.0.0")}, nil
+}
+
+func (r *platformResolver) DownloadResolvedCoord(c *model.LockedCoord) (string, error, bool) {
+	if r.downloaded == nil {
+		r.downloaded = make(map[string]*model.LockedAsset)
+	}
+	r.downloaded[c.Repo] = c.Asset
+	return r.dirs[c.Repo], nil, true
+}
+
+func (r *platformResolver) LockPlatforms(lc *model.LockedCoord, platforms []model.Platform) (bool, error) {
+	r.locked = append(r.locked, platforms)
+	lc.Platforms = make(map[string]*model.LockedAsset)
+	for _, p := range platforms {
+		lc.Platforms[p.String()] = &model.LockedAsset{Name: lc.Repo + "-" + p.String()}
+	}
+	return true, nil
+}
+
+func TestLockForPlatforms(t *testing.T) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+		ConfigFileNames:  []string{".bz.hcl"},
+	})
+	r := &platformResolver{dirs: map[string]string{"repo": filepath.Join(tmp, "pkg")}}
+	writeCacheTestFile(t, filepath.Join(tmp, "pkg", ".bz.lock"), `{}`)
+	engine.AddResolver(r)
+
+	project := filepath.Join(tmp, "project")
+	writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), `deps = ["example.com/owner/repo"]`)
+
+	_, err := engine.LockForPlatforms(project, []string{"linux/amd64", "darwin-arm64", "linux/amd64"})
+	assert.NoError(t, err)
+	lcc, err := engine.lockedConfigContentFromDir(project)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"linux-amd64", "darwin-arm64"}, lcc.Platforms)
+	assert.Equal(t, "repo-darwin-arm64", lcc.Deps[0].Platforms["darwin-arm64"].Name)
+
+	// the platforms of the previous lock are kept
+	_, err = engine.Lock(project)
+	assert.NoError(t, err)
+	lcc, err = engine.lockedConfigContentFromDir(project)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"linux-amd64", "darwin-arm64"}, lcc.Platforms)
+	assert.Len(t, lcc.Deps[0].Platforms, 2)
+	assert.Len(t, r.locked, 2)
+
+	_, err = engine.LockForPlatforms(project, []string{"linux"})
+	assert.Error(t, err)
+}
+
+func TestLockResolvedGraph(t *testing.T) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+		ConfigFileNames:  []string{".bz.hcl"},
+	})
+	r := &platformResolver{dirs: make(map[string]string)}
+	for repo, lock := range map[string]string{
+		"a": `{"deps":[{"server":"example.com","owner":"o","repo":"b","version":"1.0.0"},{"server":"example.com","owner":"o","repo":"c","version":"1.0.0"}]}`,
+		"b": `{"deps":[{"server":"example.com","owner":"o","repo":"c","version":"1.0.0"}]}`,
+		"c": `{}`,
+	} {
+		r.dirs[repo] = filepath.Join(tmp, repo)
+		writeCacheTestFile(t, filepath.Join(tmp, repo, ".bz.lock"), lock)
+	}
+	engine.AddResolver(r)
+
+	project := filepath.Join(tmp, "project")
+	writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), `deps = ["example.com/o/a", "example.com/o/c"]`)
+
+	_, err := engine.Lock(project)
+	assert.NoError(t, err)
+	lcc, err := engine.lockedConfigContentFromDir(project)
+	assert.NoError(t, err)
+	assert.Len(t, lcc.Deps, 2)
+
+	var nodes []string
+	for _, n := range lcc.Resolved {
+		nodes = append(nodes, fmt.Sprintf("%s direct=%t parents=%v", n.String(), n.Direct, n.Parents))
+	}
+	assert.Equal(t, []string{
+		"example.com/o/a@1.0.0 direct=true parents=[]",
+		"example.com/o/b@1.0.0 direct=false parents=[example.com/o/a@1.0.0]",
+		"example.com/o/c@1.0.0 direct=true parents=[example.com/o/a@1.0.0 example.com/o/b@1.0.0]",
+	}, nodes)
+
+	// transitive dependencies are downloaded with the asset of the resolved section
+	lcc.Resolved[1].Asset = &model.LockedAsset{Name: "b.tgz", Sha256: "abc"}
+	f, err := os.Create(filepath.Join(project, ".bz.lock"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewEncoder(f).Encode(lcc))
+	f.Close()
+
+	r.downloaded = nil
+	_, err = engine.ContextFromConfigDir(project)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.LockedAsset{Name: "b.tgz", Sha256: "abc"}, r.downloaded["b"])
+	assert.Nil(t, r.downloaded["a"])
+}
+
+func TestLockChanges(t *testing.T) {
+	engine, project := conflictsTestEngine(t, `deps = ["example.com/o/c@1.0"]`)
+	lock := func(config string) []string {
+		writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), config)
+		changes, err := engine.Lock(project)
+		assert.NoError(t, err, config)
+		var result []string
+		for _, c := range changes {
+			result = append(result, c.String())
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"+ example.com/o/c@1.0.0"}, lock(`deps = ["example.com/o/c@1.0"]`))
+	assert.Empty(t, lock(`deps = ["example.com/o/c@1.0"]`))
+	assert.Equal(t, []string{"~ example.com/o/c 1.0.0 -> 1.2.0"}, lock(`deps = ["example.com/o/c@1.2"]`))
+	assert.Equal(t, []string{
+		"+ example.com/o/a@1.0.0",
+		"- example.com/o/c@1.2.0",
+	}, lock(`deps = ["example.com/o/a@1"]`))
+}
+
+func TestDiffLockedCoords(t *testing.T) {
+	coord := func(repo, version string) *model.LockedCoord {
+		return &model.LockedCoord{Server: "example.com", Owner: "o", Repo: repo, Version: model.NewVersion(version)}
+	}
+	changes := diffLockedCoords(
+		[]*model.LockedCoord{coord("a", "1.0.0"), coord("b", "1.0.0"), coord("c", "1.0.0")},
+		[]*model.LockedCoord{coord("d", "1.0.0"), coord("b", "2.0.0"), coord("c", "1.0.0")},
+	)
+	assert.Equal(t, []CoordChange{
+		{Name: "example.com/o/d", New: coord("d", "1.0.0")},
+		{Name: "example.com/o/b", Old: coord("b", "1.0.0"), New: coord("b", "2.0.0")},
+		{Name: "example.com/o/a", Old: coord("a", "1.0.0")},
+	}, changes)
+	assert.Empty(t, diffLockedCoords(nil, nil))
+}
+
+func TestLockWithoutConfigHash(t *testing.T) {
+	engine, project := conflictsTestEngine(t, `deps = ["example.com/o/c@1.0"]`)
+	_, err := engine.Lock(project)
+	assert.NoError(t, err)
+
+	// written by an older bz
+	lcc, err := engine.lockedConfigContentFromDir(project)
+	assert.NoError(t, err)
+	lcc.ConfigHash = ""
+	b, err := json.Marshal(lcc)
+	assert.NoError(t, err)
+	writeCacheTestFile(t, filepath.Join(project, ".bz.lock"), string(b))
+
+	status, err := engine.lockStatus(project)
+	assert.NoError(t, err)
+	assert.False(t, status.Stale())
+	assert.Empty(t, status.Drift())
+
+	engine.appCtx.Frozen = true
+	_, err = engine.ContextFromConfigDir(project)
+	assert.NoError(t, err)
+	lcc, _ = engine.lockedConfigContentFromDir(project)
+	assert.Empty(t, lcc.ConfigHash)
+
+	// the hash is added on the next write
+	engine.appCtx.Frozen = false
+	_, err = engine.ContextFromConfigDir(project)
+	assert.NoError(t, err)
+	lcc, _ = engine.lockedConfigContentFromDir(project)
+	assert.NotEmpty(t, lcc.ConfigHash)
+
+	// real differences are still stale
+	lcc.ConfigHash = ""
+	b, _ = json.Marshal(lcc)
+	writeCacheTestFile(t, filepath.Join(project, ".bz.lock"), string(b))
+	writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), `deps = ["example.com/o/c@1.2"]`)
+	status, _ = engine.lockStatus(project)
+	assert.True(t, status.Stale())
+	assert.Equal(t, []string{"~ example.com/o/c: locked version 1.0.0 does not match `1.2`"}, status.Drift())
+}
+
+func TestContextFromResolved(t *testing.T) {
+	// a@1.0.0 requires c@1.2.0, mvs selects it for the project too
+	engine, project := conflictsTestEngine(t, `deps = ["example.com/o/c@1.0", "example.com/o/a@1"]`)
+	_, err := engine.Lock(project)
+	assert.NoError(t, err)
+	r := engine.resolvers[0].(*versionResolver)
+
+	graph := func() []string {
+		root, err := engine.ContextFromConfigDir(project)
+		assert.NoError(t, err)
+		var coords []string
+		root.Walk(func(path []*model.ResolvedDependency) bool {
+			if len(path) > 1 {
+				coords = append(coords, fmt.Sprintf("%d %s", len(path)-1, path[len(path)-1].Coord.String()))
+			}
+			return true
+		})
+		return coords
+	}
+
+	// a fresh lock file is not resolved again: the version of c required by
+	// the project is never downloaded
+	r.downloads = make(map[string]int)
+	expected := []string{"1 example.com/o/c@1.2.0", "1 example.com/o/a@1.0.0", "2 example.com/o/c@1.2.0"}
+	assert.Equal(t, expected, graph())
+	assert.Equal(t, map[string]int{"a@1.0.0": 1, "c@1.2.0": 2}, r.downloads)
+
+	// the conflicts are still reported
+	conflicts, err := engine.Conflicts(project)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+
+	// lock files without resolved section are resolved again
+	lcc, err := engine.lockedConfigContentFromDir(project)
+	assert.NoError(t, err)
+	lcc.Resolved = nil
+	b, _ := json.Marshal(lcc)
+	writeCacheTestFile(t, filepath.Join(project, ".bz.lock"), string(b))
+	r.downloads = make(map[string]int)
+	assert.Equal(t, expected, graph())
+	assert.Equal(t, 1, r.downloads["c@1.0.0"])
+}
@@ -20,13 +20,30 @@ type AppContext struct {
 	UserCacheDirName   string
 	ConfigFileNames    []string
 	UserConfig         UserConfig
+	Offline            bool // never reach the network; resolve from lock files and cache only
+	Frozen             bool // fail instead of updating a stale lock file
+	Prerelease         bool // allow pre-releases for every dependency
 }
 
 func NewDefaultAppContext() *AppContext {
+	appCtx, err := NewAppContext("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	return appCtx
+}
+
+// NewAppContext creates the application context loading the user
+// configuration from userConfigFileName.  When userConfigFileName is
+// empty it defaults to ~/.bz/config
+func NewAppContext(userConfigFileName string) (*AppContext, error) {
 	appName := "bz"
 	homeDir, _ := os.UserHomeDir()
 	userDir := filepath.Join(homeDir, fmt.Sprintf(".%s", appName))
-	userConfigFileName := filepath.Join(userDir, "config")
+	if userConfigFileName == "" {
+		userConfigFileName = filepath.Join(userDir, "config")
+	}
 
 	// load user config if it exists
 	var userConfig *UserConfig
@@ -34,8 +51,7 @@ func NewDefaultAppContext() *AppContext {
 		var err error
 		userConfig, err = NewUserConfigFromFile(userConfigFileName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading user config (%s): %s\n", userConfigFileName, err)
-			os.Exit(1)
+			return nil, fmt.Errorf("Error reading user config (%s): %w", userConfigFileName, err)
 		}
 	} else {
 		userConfig = &UserConfig{}
@@ -46,7 +62,7 @@ func NewDefaultAppContext() *AppContext {
 		LockFileName:       fmt.Sprintf(".%s.lock", appName),
 		HomeDir:            homeDir,
 		UserDir:            userDir,
-		UserConfigFileName: filepath.Join(userDir, "config"),
+		UserConfigFileName: userConfigFileName,
 		UserCacheDirName:   filepath.Join(userDir, "cache"),
 		ConfigFileNames: []string{
 			fmt.Sprintf(".%s.hcl", appName),
@@ -54,5 +70,5 @@ func NewDefaultAppContext() *AppContext {
 			fmt.Sprintf(".%s", appName),
 		},
 		UserConfig: *userConfig,
-	}
+	}, nil
 }
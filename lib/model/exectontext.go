@@ -23,6 +23,11 @@ func (o *ExecContext) Env() map[string]string {
 	m := make(map[string]string)
 	for _, s := range o.Sub {
 		for k, v := range s.Env() {
+			if k == "PATH" && m[k] != "" {
+				// keep the bin dirs of every sub dependency, in order
+				m[k] = strings.Join([]string{m[k], v}, string([]rune{os.PathListSeparator}))
+				continue
+			}
 			m[k] = v
 		}
 	}
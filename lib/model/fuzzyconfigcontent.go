@@ -4,6 +4,8 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -12,12 +14,16 @@ import (
 )
 
 type FuzzyConfigContent struct {
-	BinDir   string            `ion:"binDir" json:"binDir" hcl:"binDir,optional"`
-	Deps     []string          `ion:"deps" json:"deps" hcl:"deps,optional"`
-	Export   map[string]string `ion:"env" json:"env" hcl:"env,optional"`
-	Alias    map[string]string `ion:"alias" json:"alias" hcl:"alias,optional"`
-	Triggers *Triggers         `ion:"triggers" json:"triggers,omitempty" hcl:"triggers,block"`
-	Remain   hcl.Body          `ion:"-" json:"-" hcl:",remain"`
+	BinDir          string            `ion:"binDir" json:"binDir" hcl:"binDir,optional"`
+	Deps            []string          `ion:"deps" json:"deps" hcl:"deps,optional"`
+	AllowPrerelease bool              `ion:"allowPrerelease" json:"allowPrerelease,omitempty" hcl:"allowPrerelease,optional"`
+	Conflicts       string            `ion:"conflicts" json:"conflicts,omitempty" hcl:"conflicts,optional"` // mvs (default), highest or fail
+	Overrides       map[string]string `ion:"overrides" json:"overrides,omitempty" hcl:"overrides,optional"` // server/owner/repo => version used in the whole graph
+	Replace         map[string]string `ion:"replace" json:"replace,omitempty" hcl:"replace,optional"`       // server/owner/repo => dependency or local path used instead
+	Export          map[string]string `ion:"env" json:"env" hcl:"env,optional"`
+	Alias           map[string]string `ion:"alias" json:"alias" hcl:"alias,optional"`
+	Triggers        *Triggers         `ion:"triggers" json:"triggers,omitempty" hcl:"triggers,block"`
+	Remain          hcl.Body          `ion:"-" json:"-" hcl:",remain"`
 }
 
 func FuzzyConfigContentFromFile(f string) (*FuzzyConfigContent, error) {
@@ -43,3 +49,14 @@ func (c *FuzzyConfigContent) String() string {
 		strings.Join(exports, ";"),
 	)
 }
+
+// Hash returns a digest of the configuration.  It is stored in the lock file
+// to detect stale lock files without relying on file modification times,
+// which are not preserved by git checkouts.
+func (c *FuzzyConfigContent) Hash() string {
+	b, err := json.Marshal(c) // map keys are sorted, output is stable
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
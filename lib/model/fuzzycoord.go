@@ -14,6 +14,7 @@ type FuzzyCoord struct {
 	Owner          string // rhamerica
 	Repo           string // myrepo
 	Version        string // no v
+	Prerelease     bool   // pre-releases are allowed (allowPrerelease, --prerelease)
 }
 
 func NewCoordFromStr(depStr string) (*FuzzyCoord, error) {
@@ -47,6 +48,10 @@ func NewCoordFromStr(depStr string) (*FuzzyCoord, error) {
 		return nil, fmt.Errorf("unable to parse dependency '%s': repo name is required", depStr)
 	}
 
+	if _, err := NewVersionConstraint(version); err != nil {
+		return nil, fmt.Errorf("unable to parse dependency '%s': %w", depStr, err)
+	}
+
 	return &FuzzyCoord{
 		OriginalString: depStr,
 		Server:         server,
@@ -56,6 +61,16 @@ func NewCoordFromStr(depStr string) (*FuzzyCoord, error) {
 	}, nil
 }
 
+// Constraint returns the version constraint of the coordinate.  An invalid
+// version matches nothing
+func (d *FuzzyCoord) Constraint() VersionConstraint {
+	c, _ := NewVersionConstraint(d.Version)
+	if d.Prerelease {
+		return c.WithPrerelease()
+	}
+	return c
+}
+
 func (d *FuzzyCoord) CanonicalNameNoVersion() string {
 	return fmt.Sprintf("%s/%s/%s", d.Server, d.Owner, d.Repo)
 }
@@ -8,11 +8,17 @@ import (
 )
 
 type LockedConfigContent struct {
-	BinDir   string            `ion:"binDir" json:"binDir,omitempty"`
-	Deps     []*LockedCoord    `ion:"deps" json:"deps,omitempty"`
-	Export   map[string]string `ion:"env" json:"env,omitempty"`
-	Alias    map[string]string `ion:"alias" json:"alias,omitempty"`
-	Triggers Triggers          `ion:"triggers" json:"triggers,omitempty"`
+	ConfigHash string            `ion:"configHash" json:"configHash,omitempty"` // FuzzyConfigContent.Hash() this lock was created from
+	BinDir     string            `ion:"binDir" json:"binDir,omitempty"`
+	Deps       []*LockedCoord    `ion:"deps" json:"deps,omitempty"`
+	Export     map[string]string `ion:"env" json:"env,omitempty"`
+	Alias      map[string]string `ion:"alias" json:"alias,omitempty"`
+	Triggers   Triggers          `ion:"triggers" json:"triggers,omitempty"`
+	Platforms  []string          `ion:"platforms" json:"platforms,omitempty"` // os-arch of the assets locked in every dependency
+	Resolved   []*LockedNode     `ion:"resolved" json:"resolved,omitempty"`   // every dependency in the graph, direct and transitive
+	Conflicts  string            `ion:"conflicts" json:"conflicts,omitempty"` // policy for dependencies required at more than one version
+	Overrides  map[string]string `ion:"overrides" json:"overrides,omitempty"` // server/owner/repo => version used in the whole graph
+	Replace    map[string]string `ion:"replace" json:"replace,omitempty"`     // server/owner/repo => dependency or local path used instead
 }
 
 func LockedConfigContentFromFile(f string) (*LockedConfigContent, error) {
@@ -8,10 +8,28 @@ import (
 )
 
 type LockedCoord struct {
-	Server  string  `ion:"server" json:"server"`
-	Owner   string  `ion:"owner" json:"owner"`
-	Repo    string  `ion:"repo" json:"repo"`
-	Version Version `ion:"version" json:"version"` // no v
+	Server  string       `ion:"server" json:"server"`
+	Owner   string       `ion:"owner" json:"owner"`
+	Repo    string       `ion:"repo" json:"repo"`
+	Version Version      `ion:"version" json:"version"`       // no v
+	Asset   *LockedAsset `ion:"asset" json:"asset,omitempty"` // nil for local dependencies and old lock files
+	// asset of every target platform of the lock file, os-arch => asset
+	Platforms map[string]*LockedAsset `ion:"platforms" json:"platforms,omitempty"`
+}
+
+// LockedNode is a dependency in the flattened dependency graph of a lock file
+type LockedNode struct {
+	LockedCoord
+	Direct  bool     `ion:"direct" json:"direct,omitempty"`   // required by the configuration file
+	Parents []string `ion:"parents" json:"parents,omitempty"` // LockedCoord.String() of the dependencies requiring it
+}
+
+// LockedAsset is the release asset downloaded for a dependency
+type LockedAsset struct {
+	Name   string `ion:"name" json:"name"`
+	URL    string `ion:"url" json:"url,omitempty"`
+	Size   int64  `ion:"size" json:"size,omitempty"`
+	Sha256 string `ion:"sha256" json:"sha256,omitempty"` // hex
 }
 
 func (o *LockedCoord) isCoord() {
@@ -29,4 +47,4 @@ func (o *LockedCoord) String() string {
 		o.Repo,
 		o.Version.Canonical(),
 	)
-}
\ No newline at end of file
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package model
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Platform is an operating system and architecture.  It follows GOOS and
+// GOARCH naming conventions.  e.g.: linux/amd64
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// CurrentPlatform returns the platform bz is running on
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// NewPlatformFromStr parses os/arch or os-arch
+func NewPlatformFromStr(s string) (Platform, error) {
+	os, arch, ok := strings.Cut(s, "/")
+	if !ok {
+		os, arch, ok = strings.Cut(s, "-")
+	}
+	if !ok || os == "" || arch == "" || strings.ContainsAny(arch, "/-") {
+		return Platform{}, fmt.Errorf("invalid platform `%s`, expected os/arch.  e.g.: linux/amd64", s)
+	}
+	return Platform{OS: os, Arch: arch}, nil
+}
+
+// String returns os-arch as used in asset names.  e.g.: linux-amd64
+func (p Platform) String() string {
+	return fmt.Sprintf("%s-%s", p.OS, p.Arch)
+}
@@ -29,6 +29,97 @@ func (ed *ResolvedDependency) BinDirOrDefault() string {
 	return ed.BinDir
 }
 
+// BinDirExpanded returns the bin dir with $DIR and exported variables expanded
+func (ed *ResolvedDependency) BinDirExpanded() string {
+	return ed.resolveLocalEnvVars(nil).Env()["BINDIR"]
+}
+
+// Walk calls fn for ed and every dependency in its graph, depth first.  path
+// holds the dependencies from ed to the visited one, both included.  Sub
+// dependencies of the visited one are skipped when fn returns false
+func (ed *ResolvedDependency) Walk(fn func(path []*ResolvedDependency) bool) {
+	ed.walk(nil, fn)
+}
+
+func (ed *ResolvedDependency) walk(parents []*ResolvedDependency, fn func(path []*ResolvedDependency) bool) {
+	path := append(append([]*ResolvedDependency{}, parents...), ed)
+	if !fn(path) {
+		return
+	}
+	for _, sub := range ed.Sub {
+		sub.walk(path, fn)
+	}
+}
+
+// EnvSetting is an environment variable set by a dependency
+type EnvSetting struct {
+	Dep    *ResolvedDependency
+	Key    string
+	Value  string
+	Source string // EnvSourceExport, EnvSourceImplicit or EnvSourceBuiltin
+}
+
+const (
+	EnvSourceExport   = "env"      // `env` section of the configuration
+	EnvSourceImplicit = "implicit" // {NAME}_DIR and {NAME}_BINDIR variables
+	EnvSourceBuiltin  = "builtin"  // DIR, BINDIR, CURDIR, BZ_PROJECT_DIR
+)
+
+// EnvSettings returns every dependency in the graph that sets key, in the
+// same order ExecContext.Env() applies them.  The last one wins
+func (ed *ResolvedDependency) EnvSettings(key string) []EnvSetting {
+	var settings []EnvSetting
+	for _, sub := range ed.Sub {
+		settings = append(settings, sub.EnvSettings(key)...)
+	}
+
+	env := ed.resolveLocalEnvVars(nil).envMap
+	v, ok := env[key]
+	if !ok {
+		return settings
+	}
+
+	source := EnvSourceBuiltin
+	if _, implicit := calculateImplicitDirEnvironmentVars(*ed, env)[key]; implicit {
+		source = EnvSourceImplicit
+	} else if _, exported := ed.Exports[key]; exported && key != "BINDIR" {
+		source = EnvSourceExport
+	}
+	return append(settings, EnvSetting{Dep: ed, Key: key, Value: v, Source: source})
+}
+
+// AliasMatch is an alias applied while resolving a command
+type AliasMatch struct {
+	Dep   *ResolvedDependency
+	Name  string   // alias name
+	Value string   // alias definition
+	Args  []string // args after expanding the alias
+}
+
+// AliasChain returns the aliases applied to args, in the same order as
+// ExecContext.ResolveAlias, and the resulting args
+func (ed *ResolvedDependency) AliasChain(args []string) ([]AliasMatch, []string) {
+	var matches []AliasMatch
+	if len(args) < 1 {
+		return nil, args
+	}
+
+	if str, ok := ed.Alias[args[0]]; ok {
+		// expand this level only, with the env of this dependency
+		lvl := ExecContext{envMap: ed.Resolve().Env(), Alias: ed.Alias}
+		expanded := lvl.ResolveAlias(args)
+		matches = append(matches, AliasMatch{Dep: ed, Name: args[0], Value: str, Args: expanded})
+		args = expanded
+	}
+
+	for _, sub := range ed.Sub {
+		var subMatches []AliasMatch
+		subMatches, args = sub.AliasChain(args)
+		matches = append(matches, subMatches...)
+	}
+	return matches, args
+}
+
 // func (ed *ResolvedDependency) ResolveAlias(alias string) []string {
 // 	var result []string
 // 	if str, ok := ed.Alias[alias]; ok {
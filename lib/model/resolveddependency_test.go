@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// provenanceTestGraph returns a project that overrides a variable of java
+// and an alias that uses an alias of python
+func provenanceTestGraph() *ResolvedDependency {
+	java := &ResolvedDependency{
+		Coord:   LockedCoord{Server: "github.com", Owner: "o", Repo: "java", Version: NewVersion("17.0.1")},
+		Dir:     "/cache/java",
+		Exports: map[string]string{"JAVA_OPTS": "-Xmx1g", "JAVA_HOME": "$DIR"},
+	}
+	python := &ResolvedDependency{
+		Coord: LockedCoord{Server: "github.com", Owner: "o", Repo: "python", Version: NewVersion("3.11.1")},
+		Dir:   "/cache/python",
+		Alias: map[string]string{"python": "$DIR/bin/python3"},
+	}
+	return &ResolvedDependency{
+		Dir:     "/project",
+		Exports: map[string]string{"JAVA_OPTS": "-Xmx2g"},
+		Alias:   map[string]string{"py": "python -u"},
+		Sub:     []*ResolvedDependency{java, python},
+	}
+}
+
+func TestEnvSettings(t *testing.T) {
+	root := provenanceTestGraph()
+	java := root.Sub[0]
+
+	type setting struct {
+		dep    *ResolvedDependency
+		value  string
+		source string
+	}
+	d := map[string][]setting{
+		"JAVA_OPTS": {{java, "-Xmx1g", EnvSourceExport}, {root, "-Xmx2g", EnvSourceExport}},
+		"JAVA_HOME": {{java, "/cache/java", EnvSourceExport}},
+		"JAVA_DIR":  {{java, "/cache/java", EnvSourceImplicit}},
+		"DIR":       {{java, "/cache/java", EnvSourceBuiltin}, {root.Sub[1], "/cache/python", EnvSourceBuiltin}, {root, "/project", EnvSourceBuiltin}},
+		"MISSING":   nil,
+	}
+	env := root.Resolve().Env()
+	for key, expected := range d {
+		var actual []setting
+		for _, s := range root.EnvSettings(key) {
+			assert.Equal(t, key, s.Key)
+			actual = append(actual, setting{s.Dep, s.Value, s.Source})
+		}
+		assert.Equal(t, expected, actual, key)
+
+		// the last one is the value used
+		if len(actual) > 0 {
+			assert.Equal(t, env[key], actual[len(actual)-1].value, key)
+		}
+	}
+}
+
+func TestAliasChain(t *testing.T) {
+	root := provenanceTestGraph()
+	python := root.Sub[1]
+
+	matches, args := root.AliasChain([]string{"py", "x.py"})
+	assert.Equal(t, []AliasMatch{
+		{Dep: root, Name: "py", Value: "python -u", Args: []string{"python", "-u", "x.py"}},
+		{Dep: python, Name: "python", Value: "$DIR/bin/python3", Args: []string{"/cache/python/bin/python3", "-u", "x.py"}},
+	}, matches)
+	assert.Equal(t, []string{"/cache/python/bin/python3", "-u", "x.py"}, args)
+	assert.Equal(t, root.Resolve().ResolveAlias([]string{"py", "x.py"}), args)
+
+	// not an alias
+	matches, args = root.AliasChain([]string{"ls", "-l"})
+	assert.Empty(t, matches)
+	assert.Equal(t, []string{"ls", "-l"}, args)
+}
@@ -14,12 +14,37 @@ import (
 			token: "abcde..."
 	}
 
+	server "github.ourcompany.com" {
+			type = "github"
+			api = "https://github.ourcompany.com/api/v3/"
+			uploads = "https://github.ourcompany.com/api/uploads/"
+			token = "abcde..."
+	}
+
+	server "gitlab.ourcompany.com" {
+			type = "gitlab"
+			token = "glpat-..."
+	}
+
+	server "nodejs.org" {
+			type = "http"
+			index = "https://nodejs.org/dist/index.json"
+			download = "https://nodejs.org/dist/v{version}/node-v{version}-{os}-{arch}.{ext}"
+			binDir = "node-v{version}-{os}-{arch}/bin"
+			arch = { amd64 = "x64" }
+	}
+
 ------------
 
 	{
 		server: {
 			"github.com": {
 				token: "abcde..."
+			},
+			"github.ourcompany.com": {
+				type: "github",
+				api: "https://github.ourcompany.com/api/v3/",
+				token: "abcde..."
 			}
 		}
 	}
@@ -28,9 +53,28 @@ type UserConfig struct {
 	Servers []UserConfigServer `ion:"server" hcl:"server,block"`
 }
 
+// Server types
+const (
+	ServerTypeGithub = "github" // GitHub Enterprise Server
+	ServerTypeGitlab = "gitlab" // self-hosted GitLab
+	ServerTypeGitea  = "gitea"  // Gitea and Forgejo
+	ServerTypeHTTP   = "http"   // plain downloads from url templates
+)
+
 type UserConfigServer struct {
-	Name  string `ion:"name" hcl:",label"`
-	Token string `ion:"token" hcl:"token,optional"`
+	Name    string `ion:"name" hcl:",label"`
+	Token   string `ion:"token" hcl:"token,optional"`
+	Type    string `ion:"type" hcl:"type,optional"`       // resolver used for the dependencies of the server
+	API     string `ion:"api" hcl:"api,optional"`         // API base url, https://{server}/api/v3/ for github, https://{server}/api/v4 for gitlab, https://{server}/api/v1 for gitea
+	Uploads string `ion:"uploads" hcl:"uploads,optional"` // upload url for github, https://{server}/api/uploads/ by default
+
+	// http servers.  Templates can use {owner}, {repo}, {version}, {os}, {arch} and {ext}
+	Index       string            `ion:"index" hcl:"index,optional"`             // url of the versions of a dependency
+	IndexFormat string            `ion:"indexFormat" hcl:"indexFormat,optional"` // json (default) or html directory listing
+	Download    string            `ion:"download" hcl:"download,optional"`       // url of the asset of a version
+	BinDir      string            `ion:"binDir" hcl:"binDir,optional"`           // bin dir inside the asset when it has no lock file
+	OS          map[string]string `ion:"os" hcl:"os,optional"`                   // {os} used by the server, GOOS => os
+	Arch        map[string]string `ion:"arch" hcl:"arch,optional"`               // {arch} used by the server, GOARCH => arch
 }
 
 type UserConfigIon struct {
@@ -56,11 +100,28 @@ func NewUserConfigFromFile(f string) (*UserConfig, error) {
 }
 
 func (o *UserConfig) GetServerToken(serverName string) string {
-	var token string
-	for _, server := range o.Servers {
+	if server := o.GetServer(serverName); server != nil {
+		return server.Token
+	}
+	return ""
+}
+
+// GetServer returns the configuration of serverName, nil if it is not
+// configured.  The last one wins when it is configured more than once
+func (o *UserConfig) GetServer(serverName string) *UserConfigServer {
+	var found *UserConfigServer
+	for i, server := range o.Servers {
 		if strings.ToLower(server.Name) == strings.ToLower(serverName) {
-			token = server.Token
+			found = &o.Servers[i]
 		}
 	}
-	return token
+	return found
+}
+
+// GetServerType returns the type of serverName, empty if it is not configured
+func (o *UserConfig) GetServerType(serverName string) string {
+	if server := o.GetServer(serverName); server != nil {
+		return strings.ToLower(server.Type)
+	}
+	return ""
 }
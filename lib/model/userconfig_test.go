@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserConfigServers(t *testing.T) {
+	d := map[string]string{
+		"config": `
+server "github.com" {
+	token = "public"
+}
+
+server "GitHub.OurCompany.com" {
+	type = "github"
+	api = "https://github.ourcompany.com/api/v3/"
+	uploads = "https://uploads.ourcompany.com/"
+	token = "private"
+}
+`,
+		"config.json": `{
+	"server": {
+		"github.com": {"token": "public"},
+		"GitHub.OurCompany.com": {
+			"type": "github",
+			"api": "https://github.ourcompany.com/api/v3/",
+			"uploads": "https://uploads.ourcompany.com/",
+			"token": "private"
+		}
+	}
+}`,
+	}
+	for name, content := range d {
+		f := filepath.Join(t.TempDir(), name)
+		assert.NoError(t, os.WriteFile(f, []byte(content), 0644))
+		cfg, err := NewUserConfigFromFile(f)
+		assert.NoError(t, err, name)
+
+		assert.Equal(t, "public", cfg.GetServerToken("github.com"), name)
+		assert.Equal(t, "", cfg.GetServerType("github.com"), name)
+		assert.Equal(t, "private", cfg.GetServerToken("github.ourcompany.com"), name)
+		assert.Equal(t, ServerTypeGithub, cfg.GetServerType("github.ourcompany.com"), name)
+		server := cfg.GetServer("github.ourcompany.com")
+		if assert.NotNil(t, server, name) {
+			assert.Equal(t, "https://github.ourcompany.com/api/v3/", server.API, name)
+			assert.Equal(t, "https://uploads.ourcompany.com/", server.Uploads, name)
+		}
+		assert.Nil(t, cfg.GetServer("gitlab.com"), name)
+	}
+}
@@ -103,6 +103,14 @@ func (o *Version) Canonical() string {
 	return buf.String()
 }
 
+// Major returns the first number of the version
+func (o *Version) Major() int {
+	if len(o.nums) == 0 {
+		return 0
+	}
+	return o.nums[0]
+}
+
 func (o *Version) Original() string {
 	return o.original
 }
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// legacyPatternRegexp matches the prefix patterns supported before semver
+// ranges: 3, 3.1, 3.*, 1.2.3.4, 1.2.3-pre
+var legacyPatternRegexp = regexp.MustCompile(`^v?(\d+|\*)(\.(\d+|\*))*(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// VersionConstraint is the version requested for a dependency.  Legacy
+// prefix patterns (3 => 3.*) match every version starting with them, anything
+// else is a semver range with the npm/Cargo syntax: ^1.4, ~2.3.1, >=1.2 <2,
+// 1.x || 2.x, =1.2.3
+//
+// Pre-releases are only matched when the constraint names one (3-rc,
+// ^2.0.0-beta) or when they are allowed with WithPrerelease
+type VersionConstraint struct {
+	original   string
+	pattern    *VersionPattern     // legacy prefix pattern
+	semver     *semver.Constraints // semver range
+	prerelease bool                // match pre-releases of any version
+}
+
+// NewVersionConstraint parses s.  Empty and `0` match every version
+func NewVersionConstraint(s string) (VersionConstraint, error) {
+	s = strings.TrimSpace(s)
+	c := VersionConstraint{original: s}
+	switch {
+	case s == "" || s == "0":
+		p := NewVersionPattern("*")
+		c.pattern = &p
+	case legacyPatternRegexp.MatchString(s):
+		p := NewVersionPattern(strings.TrimPrefix(s, "v"))
+		c.pattern = &p
+	default:
+		sc, err := semver.NewConstraint(normalizeSemverConstraint(s))
+		if err != nil {
+			return c, fmt.Errorf("invalid version constraint `%s`: %w", s, err)
+		}
+		c.semver = sc
+	}
+	return c, nil
+}
+
+// Matches returns true if v satisfies the constraint.  Semver ranges only
+// look at the first three numbers of v
+func (c VersionConstraint) Matches(v Version) bool {
+	if c.pattern != nil {
+		if v.pre != "" && !c.prerelease && (c.pattern.pre == "" || !strings.HasPrefix(v.pre, c.pattern.pre)) {
+			return false // 3-rc matches 3.1.0-rc1, 3 does not
+		}
+		return c.pattern.Matches(v)
+	}
+	if c.semver == nil {
+		return false // invalid constraint
+	}
+	if c.prerelease && v.pre != "" {
+		v.pre = "" // 1.5.0-rc1 is matched as 1.5.0
+	}
+	sv, err := v.semver()
+	if err != nil {
+		return false
+	}
+	return c.semver.Check(sv)
+}
+
+// Exact returns the version to look up directly, without listing every
+// release, when the constraint names a single version: legacy patterns
+// without `*` and `=1.2.3`
+func (c VersionConstraint) Exact() (string, bool) {
+	if c.pattern != nil {
+		if c.original == "" || c.original == "0" || strings.Contains(c.original, "*") {
+			return "", false
+		}
+		return strings.TrimPrefix(c.original, "v"), true
+	}
+	if !strings.HasPrefix(c.original, "=") {
+		return "", false
+	}
+	v := strings.TrimSpace(strings.TrimPrefix(c.original, "="))
+	if !legacyPatternRegexp.MatchString(v) || strings.Contains(v, "*") {
+		return "", false
+	}
+	return strings.TrimPrefix(v, "v"), true
+}
+
+// Best returns the index of the highest version in versions that satisfies
+// the constraint, -1 when none does
+func (c VersionConstraint) Best(versions []Version) int {
+	best := -1
+	for i, v := range versions {
+		if c.Matches(v) && (best < 0 || v.Compare(versions[best]) > 0) {
+			best = i
+		}
+	}
+	return best
+}
+
+// WithPrerelease returns a copy of the constraint that also matches the
+// pre-releases of the versions it matches
+func (c VersionConstraint) WithPrerelease() VersionConstraint {
+	c.prerelease = true
+	return c
+}
+
+// Prerelease returns true if the constraint matches pre-releases, either
+// because they are allowed or because the constraint names one
+func (c VersionConstraint) Prerelease() bool {
+	if c.prerelease {
+		return true
+	}
+	if c.pattern != nil {
+		return c.pattern.pre != ""
+	}
+	return prereleaseRegexp.MatchString(c.original)
+}
+
+func (c VersionConstraint) String() string {
+	return c.original
+}
+
+// prereleaseRegexp matches a version with a pre-release in a semver range:
+// ^2.0.0-beta, >=1.4.0-0
+var prereleaseRegexp = regexp.MustCompile(`\d-[0-9A-Za-z]`)
+
+// partialLessThanRegexp matches `<` with a partial version: <2, <1.4
+var partialLessThanRegexp = regexp.MustCompile(`^<v?(\d+)(\.\d+)?$`)
+
+// normalizeSemverConstraint rewrites the npm/Cargo syntax not understood by
+// semver: comparators separated by spaces are joined with commas and `<2`
+// means `<2.0.0` instead of `<3`
+func normalizeSemverConstraint(s string) string {
+	var alternatives []string
+	for _, alt := range strings.Split(s, "||") {
+		fields := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' })
+		if containsString(fields, "-") {
+			alternatives = append(alternatives, strings.TrimSpace(alt)) // hyphen range: 1.2 - 1.4
+			continue
+		}
+
+		var comparators []string
+		for i := 0; i < len(fields); i++ {
+			c := fields[i]
+			if strings.Trim(c, "<>=!~^") == "" && i+1 < len(fields) {
+				i++
+				c += fields[i] // `>= 1.2`
+			}
+			if m := partialLessThanRegexp.FindStringSubmatch(c); m != nil {
+				c = "<" + m[1] + m[2] + strings.Repeat(".0", 2-strings.Count(m[1]+m[2], "."))
+			}
+			comparators = append(comparators, c)
+		}
+		alternatives = append(alternatives, strings.Join(comparators, ", "))
+	}
+	return strings.Join(alternatives, " || ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// semver converts the version to a semver version, keeping the first three numbers
+func (o *Version) semver() (*semver.Version, error) {
+	nums := []int{0, 0, 0}
+	copy(nums, o.nums)
+	s := fmt.Sprintf("%d.%d.%d", nums[0], nums[1], nums[2])
+	if o.pre != "" {
+		s = fmt.Sprintf("%s-%s", s, o.pre)
+	}
+	return semver.NewVersion(s)
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionConstraintMatches(t *testing.T) {
+	d := map[string]struct {
+		match   []string
+		noMatch []string
+	}{
+		"":             {match: []string{"0.1", "1.2.3", "4.0.0.1"}},
+		"3":            {match: []string{"3", "3.0.1", "3.11.2.1"}, noMatch: []string{"2.7.18", "4.0.0"}},
+		"3.*":          {match: []string{"3.1"}, noMatch: []string{"4.1"}},
+		"v1.2":         {match: []string{"1.2.9"}, noMatch: []string{"1.3.0"}},
+		"^1.4":         {match: []string{"1.4.0", "1.9.2"}, noMatch: []string{"1.3.9", "2.0.0"}},
+		"~2.3.1":       {match: []string{"2.3.1", "2.3.9"}, noMatch: []string{"2.3.0", "2.4.0"}},
+		">=1.2 <2":     {match: []string{"1.2.0", "1.99.0"}, noMatch: []string{"1.1.9", "2.0.0"}},
+		">=1.2, <2":    {match: []string{"1.5.0"}, noMatch: []string{"2.0.1"}},
+		"1.x || 2.x":   {match: []string{"1.0.0", "2.5.1"}, noMatch: []string{"0.9.0", "3.0.0"}},
+		"=1.2.3":       {match: []string{"1.2.3", "v1.2.3"}, noMatch: []string{"1.2.4", "1.2"}},
+		"1.2 - 1.4.5":  {match: []string{"1.3.0", "1.4.5"}, noMatch: []string{"1.4.6"}},
+		"<2":           {match: []string{"1.9.9"}, noMatch: []string{"2.0.0", "2.0.1"}},
+		">= 1.2 < 1.4": {match: []string{"1.3.9"}, noMatch: []string{"1.4.0"}},
+		"^1.4.0-0":     {match: []string{"1.5.0-rc1", "1.5.0"}, noMatch: []string{"2.0.0-rc1"}},
+	}
+	for constraintStr, test := range d {
+		c, err := NewVersionConstraint(constraintStr)
+		assert.NoError(t, err, constraintStr)
+		for _, v := range test.match {
+			assert.True(t, c.Matches(NewVersion(v)), "%s should match %s", constraintStr, v)
+		}
+		for _, v := range test.noMatch {
+			assert.False(t, c.Matches(NewVersion(v)), "%s should not match %s", constraintStr, v)
+		}
+	}
+
+	_, err := NewVersionConstraint("^^1")
+	assert.Error(t, err)
+	_, err = NewCoordFromStr("github.com/bazurto/python@>=nope")
+	assert.ErrorContains(t, err, "invalid version constraint")
+}
+
+func TestVersionConstraintExact(t *testing.T) {
+	d := map[string]string{
+		"3":      "3",
+		"v1.2.3": "1.2.3",
+		"=1.2.3": "1.2.3",
+		"3.*":    "",
+		"":       "",
+		"^1.4":   "",
+		">=1.2":  "",
+	}
+	for constraintStr, expected := range d {
+		c, err := NewVersionConstraint(constraintStr)
+		assert.NoError(t, err)
+		exact, ok := c.Exact()
+		assert.Equal(t, expected, exact, constraintStr)
+		assert.Equal(t, expected != "", ok, constraintStr)
+	}
+}
+
+func TestVersionConstraintBest(t *testing.T) {
+	var versions []Version
+	for _, v := range []string{"1.2.0", "1.10.0", "1.9.0", "2.0.0", "0.9.0"} {
+		versions = append(versions, NewVersion(v))
+	}
+
+	c, _ := NewVersionConstraint("^1.2")
+	assert.Equal(t, 1, c.Best(versions)) // 1.10.0, not the last one found
+
+	c, _ = NewVersionConstraint("1")
+	assert.Equal(t, 1, c.Best(versions))
+
+	c, _ = NewVersionConstraint(">=3")
+	assert.Equal(t, -1, c.Best(versions))
+}
+
+func TestVersionConstraintPrerelease(t *testing.T) {
+	d := map[string]struct {
+		match   []string
+		noMatch []string
+	}{
+		"":          {match: []string{"3.0.0"}, noMatch: []string{"3.1.0-rc1"}},
+		"3":         {match: []string{"3.0.0"}, noMatch: []string{"3.1.0-rc1"}},
+		"3-rc":      {match: []string{"3.0.0", "3.1.0-rc1", "3.1.0-rc.2"}, noMatch: []string{"3.1.0-beta1", "4.0.0-rc1"}},
+		"^3":        {match: []string{"3.0.0"}, noMatch: []string{"3.1.0-rc1"}},
+		"^3.1.0-rc": {match: []string{"3.1.0-rc1", "3.2.0"}, noMatch: []string{"4.0.0-rc1"}},
+	}
+	for constraintStr, test := range d {
+		c, err := NewVersionConstraint(constraintStr)
+		assert.NoError(t, err, constraintStr)
+		for _, v := range test.match {
+			assert.True(t, c.Matches(NewVersion(v)), "%s should match %s", constraintStr, v)
+		}
+		for _, v := range test.noMatch {
+			assert.False(t, c.Matches(NewVersion(v)), "%s should not match %s", constraintStr, v)
+		}
+	}
+
+	c, _ := NewVersionConstraint("3")
+	assert.False(t, c.Prerelease())
+	assert.True(t, c.WithPrerelease().Prerelease())
+	assert.True(t, c.WithPrerelease().Matches(NewVersion("3.1.0-rc1")))
+	assert.False(t, c.WithPrerelease().Matches(NewVersion("4.0.0-rc1")))
+	c, _ = NewVersionConstraint("^3")
+	assert.True(t, c.WithPrerelease().Matches(NewVersion("3.1.0-rc1")))
+	c, _ = NewVersionConstraint(">=1.4.0-0")
+	assert.True(t, c.Prerelease())
+
+	fc, _ := NewCoordFromStr("github.com/bazurto/python@3")
+	fc.Prerelease = true
+	assert.True(t, fc.Constraint().Matches(NewVersion("3.12.0-rc1")))
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+)
+
+// OutdatedDep compares the locked version of a dependency with the
+// versions available
+type OutdatedDep struct {
+	Name       string `json:"name"`       // server/owner/repo
+	Constraint string `json:"constraint"` // version in the configuration file
+	Locked     string `json:"locked"`     // version in the lock file, empty if not locked
+	Wanted     string `json:"wanted"`     // newest version matching Constraint
+	Latest     string `json:"latest"`     // newest version
+}
+
+// IsOutdated returns true when a newer version than the locked one exists
+func (d *OutdatedDep) IsOutdated() bool {
+	return d.Locked != d.Wanted || d.Locked != d.Latest
+}
+
+// Outdated lists the direct dependencies of the project in dir with the newest
+// versions available.  Dependencies whose resolver cannot list versions
+// (e.g. local) are not included.
+func (o *Engine) Outdated(dir string) ([]*OutdatedDep, error) {
+	status, err := o.lockStatus(dir)
+	if err != nil {
+		return nil, err
+	}
+	if status.Config == nil {
+		return nil, fmt.Errorf("no configuration file found in %s", dir)
+	}
+	if status.Lock == nil {
+		return nil, fmt.Errorf("%s: %w", status.LockFile, status.LockErr)
+	}
+
+	var result []*OutdatedDep
+	for _, dep := range status.Config.Deps {
+		fc, err := newFuzzyCoord(dep, status.Config, o.appCtx.Prerelease)
+		if err != nil {
+			return nil, err
+		}
+
+		versions, handled, err := o.listVersions(fc)
+		if err != nil {
+			return nil, err
+		}
+		if !handled {
+			Debug.Printf("Outdated(): no resolver can list versions for %s", fc)
+			continue
+		}
+
+		d := &OutdatedDep{Name: fc.CanonicalNameNoVersion(), Constraint: fc.Version}
+		for _, c := range status.Lock.Deps {
+			if sameDependency(fc, c) {
+				d.Locked = c.Version.Canonical()
+				break
+			}
+		}
+
+		if i := fc.Constraint().Best(versions); i >= 0 {
+			d.Wanted = versions[i].Canonical()
+		}
+		latest, _ := model.NewVersionConstraint("")
+		if fc.Prerelease {
+			latest = latest.WithPrerelease()
+		}
+		if i := latest.Best(versions); i >= 0 {
+			d.Latest = versions[i].Canonical()
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// listVersions asks the resolvers that implement resolver.VersionLister for
+// the versions of fc
+func (o *Engine) listVersions(fc *model.FuzzyCoord) ([]model.Version, bool, error) {
+	for _, r := range o.resolvers {
+		lister, ok := r.(resolver.VersionLister)
+		if !ok {
+			continue
+		}
+		versions, handled, err := lister.ListVersions(fc)
+		if err != nil {
+			return nil, true, err
+		}
+		if handled {
+			return versions, true, nil
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// listingResolver lists the versions of the example.com packages in repos
+type listingResolver struct {
+	versions map[string][]string
+}
+
+func (r *listingResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord, error) {
+	return nil, nil
+}
+
+func (r *listingResolver) DownloadResolvedCoord(c *model.LockedCoord) (string, error, bool) {
+	return "", nil, false
+}
+
+func (r *listingResolver) ListVersions(c *model.FuzzyCoord) ([]model.Version, bool, error) {
+	names, ok := r.versions[c.Repo]
+	if c.Server != "example.com" || !ok {
+		return nil, false, nil
+	}
+	var versions []model.Version
+	for _, v := range names {
+		versions = append(versions, model.NewVersion(v))
+	}
+	return versions, true, nil
+}
+
+func TestOutdated(t *testing.T) {
+	engine, project := conflictsTestEngine(t, "")
+	localc := filepath.Join(filepath.Dir(project), "localc")
+	writeCacheTestFile(t, filepath.Join(project, ".bz.hcl"), fmt.Sprintf(`deps = ["example.com/o/c@1.0", "example.com/o/a@1", "local%s"]`, localc))
+	engine.AddResolver(&listingResolver{versions: map[string][]string{"c": {"1.0.0", "1.0.1", "1.2.0", "2.0.0-rc1"}}})
+
+	// not locked
+	_, err := engine.Outdated(project)
+	assert.Error(t, err)
+
+	_, err = engine.Lock(project)
+	assert.NoError(t, err)
+
+	// a cannot be listed and localc is local, they are skipped
+	deps, err := engine.Outdated(project)
+	assert.NoError(t, err)
+	if assert.Len(t, deps, 1) {
+		assert.Equal(t, &OutdatedDep{
+			Name:       "example.com/o/c",
+			Constraint: "1.0",
+			Locked:     "1.0.0",
+			Wanted:     "1.0.1",
+			Latest:     "1.2.0",
+		}, deps[0])
+		assert.True(t, deps[0].IsOutdated())
+	}
+
+	// prereleases are only latest when allowed
+	engine.appCtx.Prerelease = true
+	deps, err = engine.Outdated(project)
+	assert.NoError(t, err)
+	if assert.Len(t, deps, 1) {
+		assert.Equal(t, "1.0.1", deps[0].Wanted)
+		assert.Equal(t, "2.0.0-rc1", deps[0].Latest)
+	}
+
+	assert.False(t, (&OutdatedDep{Locked: "1.2.0", Wanted: "1.2.0", Latest: "1.2.0"}).IsOutdated())
+}
@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// ChecksumsFileName is written next to the archives created by Pack
+const ChecksumsFileName = resolver.ChecksumsFileName
+
+// PackOptions describes the archives created by Pack
+type PackOptions struct {
+	Name      string   // package name, defaults to the directory name
+	Version   string   // defaults to the content of the VERSION file
+	Platforms []string // os/arch.  None or `any` creates an archive for every platform
+	Format    string   // tgz or zip
+	Include   []string // ant patterns, {os} and {arch} are replaced for every platform.  Defaults to everything
+	Exclude   []string // ant patterns, {os} and {arch} are replaced for every platform
+	OutDir    string   // defaults to dist
+}
+
+// PackedArchive is an archive created by Pack
+type PackedArchive struct {
+	File     string // full path
+	Platform string // os-arch, empty for every platform
+	Sha256   string // hex
+	Size     int64
+}
+
+// Pack locks the package in dir and creates one archive per platform in
+// OutDir named after the bz asset naming conventions:  {name}-{os}-{arch}-v{version}.
+// The checksums of the archives are written to checksums.txt in OutDir
+func (o *Engine) Pack(dir string, opts PackOptions) ([]*PackedArchive, error) {
+	dir = utils.FsAbs(dir)
+	opts, err := o.packDefaults(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// .bz.lock is required at the root of the archive
+	if _, found := o.findFuzzyConfigFile(dir); found {
+		if _, err := o.Lock(dir); err != nil {
+			return nil, err
+		}
+	} else if !utils.FileExists(filepath.Join(dir, o.appCtx.LockFileName)) {
+		return nil, fmt.Errorf("no configuration file found in %s", dir)
+	}
+
+	if err := utils.MkdirIfNotExists(opts.OutDir); err != nil {
+		return nil, err
+	}
+
+	var archives []*PackedArchive
+	for _, p := range opts.Platforms {
+		var platform *model.Platform
+		if p != "any" {
+			pl, err := model.NewPlatformFromStr(p)
+			if err != nil {
+				return nil, err
+			}
+			platform = &pl
+		}
+
+		archive, err := o.packPlatform(dir, opts, platform)
+		if err != nil {
+			return archives, err
+		}
+		archives = append(archives, archive)
+	}
+
+	if err := writeChecksums(filepath.Join(opts.OutDir, ChecksumsFileName), opts.Version, archives); err != nil {
+		return archives, err
+	}
+	return archives, nil
+}
+
+func (o *Engine) packDefaults(dir string, opts PackOptions) (PackOptions, error) {
+	if opts.Name == "" {
+		opts.Name = filepath.Base(dir)
+	}
+	version, err := packageVersion(dir, opts.Version)
+	if err != nil {
+		return opts, err
+	}
+	opts.Version = version
+	if len(opts.Platforms) == 0 {
+		opts.Platforms = []string{"any"}
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = "tgz"
+	case "tgz", "zip":
+	default:
+		return opts, fmt.Errorf("unknown format `%s`, expected tgz or zip", opts.Format)
+	}
+	if opts.OutDir == "" {
+		opts.OutDir = filepath.Join(dir, "dist")
+	}
+	opts.OutDir = utils.FsAbs(opts.OutDir)
+
+	// never archive the archives or git
+	opts.Exclude = append(opts.Exclude, ".git")
+	if rel, err := filepath.Rel(dir, opts.OutDir); err == nil && !strings.HasPrefix(rel, "..") {
+		opts.Exclude = append(opts.Exclude, filepath.ToSlash(rel))
+	}
+	if len(opts.Include) > 0 {
+		opts.Include = append([]string{o.appCtx.LockFileName}, opts.Include...)
+	}
+	return opts, nil
+}
+
+// packageVersion returns version without the `v` prefix.  When version is
+// empty it is read from the VERSION file in dir
+func packageVersion(dir, version string) (string, error) {
+	if version == "" {
+		b, err := os.ReadFile(filepath.Join(dir, "VERSION"))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		version = strings.TrimSpace(string(b))
+	}
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return "", fmt.Errorf("version is required, set it with a VERSION file")
+	}
+	return version, nil
+}
+
+// packPlatform creates the archive of platform.  nil means every platform
+func (o *Engine) packPlatform(dir string, opts PackOptions, platform *model.Platform) (*PackedArchive, error) {
+	replace := func(patterns []string) []string {
+		if platform == nil {
+			return patterns
+		}
+		r := strings.NewReplacer("{os}", platform.OS, "{arch}", platform.Arch)
+		var res []string
+		for _, p := range patterns {
+			res = append(res, r.Replace(p))
+		}
+		return res
+	}
+	include := replace(opts.Include)
+	exclude := replace(opts.Exclude)
+
+	name := fmt.Sprintf("%s.%s", resolver.AssetCanonicalName(opts.Name, platform, opts.Version), opts.Format)
+	archive := &PackedArchive{File: filepath.Join(opts.OutDir, name)}
+	if platform != nil {
+		archive.Platform = platform.String()
+	}
+
+	tmp := fmt.Sprintf("%s.tmp", archive.File)
+	err := func() error {
+		w, err := os.Create(tmp)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		h := sha256.New()
+		counter := &countingWriter{}
+		mw := io.MultiWriter(w, h, counter)
+		if opts.Format == "zip" {
+			err = utils.Zip(dir, mw, include, exclude)
+		} else {
+			err = utils.Tgz(dir, mw, include, exclude)
+		}
+		if err != nil {
+			return err
+		}
+		archive.Sha256 = fmt.Sprintf("%x", h.Sum(nil))
+		archive.Size = counter.n
+		return w.Close()
+	}()
+	if err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("packPlatform(%s): %w", name, err)
+	}
+	if err := os.Rename(tmp, archive.File); err != nil {
+		return nil, fmt.Errorf("packPlatform(%s): %w", name, err)
+	}
+	return archive, nil
+}
+
+// writeChecksums adds the checksums of archives to file keeping the checksums
+// of the other archives of version already in it, e.g. packed on another
+// machine.  The archives of other versions are dropped
+func writeChecksums(file, version string, archives []*PackedArchive) error {
+	previous, err := ReadChecksums(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	checksums := make(map[string]string)
+	for name, sum := range previous {
+		if isVersionAsset(name, version) {
+			checksums[name] = sum
+		}
+	}
+	for _, a := range archives {
+		checksums[filepath.Base(a.File)] = a.Sha256
+	}
+
+	var names []string
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", checksums[name], name)
+	}
+	return os.WriteFile(file, []byte(b.String()), 0644)
+}
+
+// isVersionAsset returns true if name is an asset of version according to
+// the bz asset naming conventions: {name}[-{os}-{arch}]-v{version}.{ext}
+func isVersionAsset(name, version string) bool {
+	return strings.Contains(name, fmt.Sprintf("-v%s.", version))
+}
+
+// ReadChecksums reads a sha256sum formatted file and returns file name => hex digest
+func ReadChecksums(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return resolver.ParseChecksums(f)
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPack(t *testing.T) {
+	tmp := t.TempDir()
+	engine := NewEngine(model.AppContext{
+		LockFileName:     ".bz.lock",
+		UserCacheDirName: filepath.Join(tmp, "cache"),
+		ConfigFileNames:  []string{".bz.hcl", ".bz.json", ".bz"},
+	})
+
+	dir := filepath.Join(tmp, "tool")
+	writeCacheTestFile(t, filepath.Join(dir, ".bz.hcl"), "deps = []\n")
+	writeCacheTestFile(t, filepath.Join(dir, "VERSION"), "v1.2.3\n")
+	writeCacheTestFile(t, filepath.Join(dir, "build", "linux-amd64", "tool"), "linux")
+	writeCacheTestFile(t, filepath.Join(dir, "build", "windows-amd64", "tool.exe"), "windows")
+	writeCacheTestFile(t, filepath.Join(dir, "dist", "old.tgz"), "")
+	writeCacheTestFile(t, filepath.Join(dir, "dist", ChecksumsFileName), "aaa  tool-v1.2.2.tgz\nbbb  tool-darwin-arm64-v1.2.3.tgz\n")
+
+	archives, err := engine.Pack(dir, PackOptions{
+		Platforms: []string{"linux/amd64", "windows/amd64"},
+		Include:   []string{"build/{os}-{arch}"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, archives, 2)
+	assert.Equal(t, filepath.Join(dir, "dist", "tool-linux-amd64-v1.2.3.tgz"), archives[0].File)
+	assert.Equal(t, "linux-amd64", archives[0].Platform)
+
+	extracted := filepath.Join(tmp, "extracted")
+	assert.NoError(t, utils.Untgz(archives[1].File, extracted))
+	assert.FileExists(t, filepath.Join(extracted, ".bz.lock"))
+	assert.FileExists(t, filepath.Join(extracted, "build", "windows-amd64", "tool.exe"))
+	assert.NoFileExists(t, filepath.Join(extracted, "build", "linux-amd64", "tool"))
+
+	checksums, err := ReadChecksums(filepath.Join(dir, "dist", ChecksumsFileName))
+	assert.NoError(t, err)
+	// archives of other versions are dropped, the ones packed elsewhere are kept
+	assert.Equal(t, map[string]string{
+		"tool-darwin-arm64-v1.2.3.tgz":  "bbb",
+		"tool-linux-amd64-v1.2.3.tgz":   archives[0].Sha256,
+		"tool-windows-amd64-v1.2.3.tgz": archives[1].Sha256,
+	}, checksums)
+}
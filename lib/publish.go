@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/resolver"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// PublishOptions describes where Publish uploads the archives
+type PublishOptions struct {
+	Repo    string // [server/]owner/repo, defaults to the origin remote of the git repository
+	Version string // defaults to the content of the VERSION file
+	DistDir string // directory with the archives and checksums to upload, defaults to dist
+	APIURL  string // API base url, defaults to the GitHub API.  Required for other servers
+	Force   bool   // replace assets that already exist
+}
+
+// Publish uploads the archives and checksums created by Pack in opts.DistDir
+// to the release v{version} of the repository
+func (o *Engine) Publish(dir string, opts PublishOptions) ([]*resolver.PublishedAsset, error) {
+	if o.appCtx.Offline {
+		return nil, fmt.Errorf("cannot publish in offline mode")
+	}
+
+	dir = utils.FsAbs(dir)
+	version, err := packageVersion(dir, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Repo == "" {
+		opts.Repo = gitOriginRepo(dir)
+		if opts.Repo == "" {
+			return nil, fmt.Errorf("repository is required, unable to find it in the git origin remote of %s", dir)
+		}
+	}
+	if strings.Count(opts.Repo, "/") == 1 {
+		opts.Repo = "github.com/" + opts.Repo
+	}
+	fc, err := model.NewCoordFromStr(opts.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if !resolver.IsGithubServer(&o.appCtx, fc.Server) && opts.APIURL == "" {
+		return nil, fmt.Errorf("the API url of %s is required, or configure it as a github server in %s", fc.Server, o.appCtx.UserConfigFileName)
+	}
+
+	if opts.DistDir == "" {
+		opts.DistDir = filepath.Join(dir, "dist")
+	}
+	files, err := publishFiles(utils.FsAbs(opts.DistDir), version)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := resolver.NewGithubPublisher(&o.appCtx, fc.Server, opts.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	return publisher.Publish(fc.Owner, fc.Repo, fmt.Sprintf("v%s", version), files, opts.Force)
+}
+
+// publishFiles returns the files of version in dir sorted by name: the
+// checksums file and the archives of version it lists, so archives left by
+// packing other versions are not published.  Without checksums file every
+// file is published, skipping hidden and temporary files
+func publishFiles(dir, version string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("nothing to publish, %s does not exist", dir)
+	} else if err != nil {
+		return nil, fmt.Errorf("publishFiles(): %w", err)
+	}
+
+	checksums, err := ReadChecksums(filepath.Join(dir, ChecksumsFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("publishFiles(): %w", err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if checksums != nil && name != ChecksumsFileName {
+			if _, listed := checksums[name]; !listed || !isVersionAsset(name, version) {
+				Debug.Printf("publishFiles(): skipping %s, not an archive of v%s in %s", name, version, ChecksumsFileName)
+				continue
+			}
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("nothing to publish in %s", dir)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+var gitRemoteUrlRegexp = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^:/]+)(?::\d+)?[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// gitOriginRepo returns server/owner/repo of the origin remote in the git
+// configuration of dir.  Empty when it cannot be found
+func gitOriginRepo(dir string) string {
+	f, err := os.Open(filepath.Join(dir, ".git", "config"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	inOrigin := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inOrigin = line == `[remote "origin"]`
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inOrigin || !ok || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		m := gitRemoteUrlRegexp.FindStringSubmatch(strings.TrimSpace(value))
+		if m == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s/%s/%s", m[1], m[2], m[3])
+	}
+	return ""
+}
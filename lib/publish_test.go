@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitOriginRepo(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/owner/repo.git":       "github.com/owner/repo",
+		"https://github.com/owner/repo":           "github.com/owner/repo",
+		"git@github.com:owner/repo.git":           "github.com/owner/repo",
+		"ssh://git@git.example.com:22/owner/repo": "git.example.com/owner/repo",
+		"/some/local/path":                        "",
+	}
+	for url, expected := range tests {
+		dir := t.TempDir()
+		writeCacheTestFile(t, filepath.Join(dir, ".git", "config"), `[core]
+	bare = false
+[remote "upstream"]
+	url = https://github.com/other/repo.git
+[remote "origin"]
+	url = `+url+`
+	fetch = +refs/heads/*:refs/remotes/origin/*
+`)
+		assert.Equal(t, expected, gitOriginRepo(dir), url)
+	}
+	assert.Equal(t, "", gitOriginRepo(t.TempDir()))
+}
+
+func TestPublishFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b-v1.tgz", "a-v1.tgz", "c-v1.tgz.tmp", ".hidden"} {
+		writeCacheTestFile(t, filepath.Join(dir, name), name)
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	// without checksums file
+	files, err := publishFiles(dir, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a-v1.tgz"),
+		filepath.Join(dir, "b-v1.tgz"),
+	}, files)
+
+	// only the archives of the version listed in the checksums file
+	writeCacheTestFile(t, filepath.Join(dir, "a-v0.tgz"), "old")
+	writeCacheTestFile(t, filepath.Join(dir, ChecksumsFileName), "aaa  a-v1.tgz\nbbb  b-v1.tgz\nccc  a-v0.tgz\n")
+	assert.NoError(t, os.Remove(filepath.Join(dir, "b-v1.tgz")))
+	writeCacheTestFile(t, filepath.Join(dir, "notes.txt"), "not packed")
+	files, err = publishFiles(dir, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a-v1.tgz"),
+		filepath.Join(dir, ChecksumsFileName),
+	}, files)
+
+	_, err = publishFiles(filepath.Join(dir, "sub"), "1")
+	assert.ErrorContains(t, err, "nothing to publish")
+}
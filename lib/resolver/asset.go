@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// AssetMetadataFileName is written in the cache version dir, next to the
+// downloaded archive, with the LockedAsset that was extracted
+const AssetMetadataFileName = ".asset.json"
+
+// ReadAssetMetadata reads the asset downloaded to the cache version dir
+func ReadAssetMetadata(dir string) (*model.LockedAsset, error) {
+	b, err := os.ReadFile(filepath.Join(dir, AssetMetadataFileName))
+	if err != nil {
+		return nil, err
+	}
+	var asset model.LockedAsset
+	if err := json.Unmarshal(b, &asset); err != nil {
+		return nil, fmt.Errorf("ReadAssetMetadata(): %w", err)
+	}
+	return &asset, nil
+}
+
+func writeAssetMetadata(dir string, asset *model.LockedAsset) error {
+	b, err := json.MarshalIndent(asset, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, AssetMetadataFileName), b, 0644)
+}
+
+// cacheVersionDir returns the dir where the version of lc is downloaded.  The
+// archive is extracted to the "extracted" dir inside it
+func cacheVersionDir(appCtx *model.AppContext, lc *model.LockedCoord) string {
+	return filepath.Join(
+		appCtx.UserCacheDirName,
+		"deps",
+		lc.Server,
+		lc.Owner,
+		lc.Repo,
+		fmt.Sprintf("v%s", lc.Version.Canonical()),
+	)
+}
+
+// downloadAsset downloads asset, read from open, to the cache version dir,
+// verifies it against the lock file and extracts it to dir/extracted.  The
+// size and digest of asset are set from the downloaded bits
+func downloadAsset(appCtx *model.AppContext, lc *model.LockedCoord, dir string, asset *model.LockedAsset, open func() (io.ReadCloser, error)) error {
+	if err := utils.MkdirIfNotExists(dir); err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, asset.Name)
+	downloadFileTmp := fmt.Sprintf("%s.tmp", file)
+	err := func() error {
+		w, err := os.Create(downloadFileTmp)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		readCloser, err := open()
+		if err != nil {
+			return err
+		}
+		defer readCloser.Close()
+		Info.Printf("Downloading file %s ...", file)
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(w, h), readCloser)
+		if err != nil {
+			return err
+		}
+		asset.Size = n
+		asset.Sha256 = fmt.Sprintf("%x", h.Sum(nil))
+		return nil
+	}()
+	if err != nil {
+		os.Remove(downloadFileTmp)
+		return err
+	}
+
+	// never extract bits that differ from the lock file
+	if err := verifyAsset(appCtx, lc, asset); err != nil {
+		os.Remove(downloadFileTmp)
+		return err
+	}
+
+	// rename tmp download file to downloadFile
+	if err := os.Rename(downloadFileTmp, file); err != nil {
+		return err
+	}
+	Info.Printf("Downloading file %s DONE", file)
+
+	if err := extractAsset(file, filepath.Join(dir, "extracted")); err != nil {
+		return fmt.Errorf("unable to extract dependency: %w", err)
+	}
+	if err := writeAssetMetadata(dir, asset); err != nil {
+		Warn.Printf("unable to write %s: %s", filepath.Join(dir, AssetMetadataFileName), err)
+	}
+	return nil
+}
+
+// extractAsset extracts the zip or tgz file to extractToDir.  Other files are
+// binaries, copied to extractToDir as executables
+func extractAsset(file string, extractToDir string) error {
+	switch {
+	case strings.HasSuffix(file, ".zip"):
+		return utils.Unzip(file, extractToDir)
+	case strings.HasSuffix(file, ".tgz"), strings.HasSuffix(file, ".tar.gz"):
+		return utils.Untgz(file, extractToDir)
+	}
+
+	if err := utils.MkdirIfNotExists(extractToDir); err != nil {
+		return err
+	}
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(filepath.Join(extractToDir, filepath.Base(file)), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// verifyAsset checks the asset that was downloaded for lc against the assets
+// in the lock file: the asset of the current platform and the asset downloaded
+// when the lock file was written.  Missing digests are recorded in lc so they
+// are written to the lock file.
+//
+// When the lock file was written on another platform without locking the
+// current one the downloaded asset cannot be verified.  That is an error in
+// frozen mode
+func verifyAsset(appCtx *model.AppContext, lc *model.LockedCoord, downloaded *model.LockedAsset) error {
+	if lc.Asset == nil {
+		lc.Asset = downloaded
+	}
+
+	locked := []*model.LockedAsset{lc.Asset}
+	if a, ok := lc.Platforms[model.CurrentPlatform().String()]; ok {
+		locked = append(locked, a)
+	}
+
+	found := false
+	for _, a := range locked {
+		if a.Name != downloaded.Name {
+			continue
+		}
+		found = true
+		if a.Sha256 == "" {
+			a.Sha256 = downloaded.Sha256
+		} else if a.Sha256 != downloaded.Sha256 {
+			return fmt.Errorf(
+				"%s: sha256 mismatch for %s: lock file has %s, downloaded %s",
+				lc, downloaded.Name, a.Sha256, downloaded.Sha256,
+			)
+		}
+	}
+	if !found {
+		// locked on another platform without `bz :lock --platform`
+		current := model.CurrentPlatform()
+		msg := fmt.Sprintf(
+			"%s: locked asset %s differs from %s, the asset of %s is not locked.  Run `bz :lock --platform %s/%s`",
+			lc, lc.Asset.Name, downloaded.Name, current, current.OS, current.Arch,
+		)
+		if appCtx.Frozen {
+			return fmt.Errorf("%s", msg)
+		}
+		Warn.Printf("%s, digest not verified", msg)
+	}
+	return nil
+}
+
+// verifyCachedAsset checks the asset already downloaded to the cache version
+// dir against the asset in the lock file.  Cache entries created before digests
+// were recorded are hashed from the archive kept in dir
+func verifyCachedAsset(appCtx *model.AppContext, lc *model.LockedCoord, dir string) error {
+	cached, err := ReadAssetMetadata(dir)
+	if os.IsNotExist(err) {
+		locked := lc.Asset
+		if a, ok := lc.Platforms[model.CurrentPlatform().String()]; ok {
+			locked = a
+		}
+		if locked == nil {
+			return nil // nothing to verify against
+		}
+		archive := filepath.Join(dir, locked.Name)
+		if !utils.FileExists(archive) {
+			return nil
+		}
+		digest, err := utils.FileSha256(archive)
+		if err != nil {
+			return fmt.Errorf("verifyCachedAsset(): %w", err)
+		}
+		cached = &model.LockedAsset{Name: locked.Name, URL: locked.URL, Size: locked.Size, Sha256: digest}
+		if err := writeAssetMetadata(dir, cached); err != nil {
+			Warn.Printf("unable to write %s: %s", filepath.Join(dir, AssetMetadataFileName), err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("verifyCachedAsset(): %w", err)
+	}
+
+	if err := verifyAsset(appCtx, lc, cached); err != nil {
+		return fmt.Errorf("%w (cached in %s)", err, dir)
+	}
+	return nil
+}
+
+// ChecksumsFileName is the release asset with the digests of the other
+// assets.  It uses the sha256sum format: <hex digest>  <file name>
+const ChecksumsFileName = "checksums.txt"
+
+// isChecksumsAsset returns true for checksums.txt and the names used by
+// other release tools: SHA256SUMS, {name}_{version}_checksums.txt
+func isChecksumsAsset(name string) bool {
+	return name == "SHA256SUMS" || strings.HasSuffix(name, ChecksumsFileName)
+}
+
+// ParseChecksums reads the sha256sum format and returns file name => hex digest
+func ParseChecksums(r io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = fields[0] // * is binary mode
+	}
+	return checksums, scanner.Err()
+}
@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+	"github.com/google/go-github/v47/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGithubRelease serves release v1.0.0 of owner/repo with a single asset
+func fakeGithubRelease(t *testing.T, content []byte) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/releases/tags/v1.0.0":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":   1,
+				"name": "v1.0.0",
+				"assets": []map[string]interface{}{{
+					"id":                   2,
+					"name":                 "repo-v1.0.0.tgz",
+					"size":                 len(content),
+					"browser_download_url": server.URL + "/download/repo-v1.0.0.tgz",
+				}},
+			})
+		case "/repos/owner/repo/releases/assets/2":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(content)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	githubClientMap["github.com"] = client
+	t.Cleanup(func() { delete(githubClientMap, "github.com") })
+}
+
+func testTgz(t *testing.T) []byte {
+	src := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(src, ".bz.lock"), []byte("{}"), 0644))
+	var b bytes.Buffer
+	assert.NoError(t, utils.Tgz(src, &b, nil, nil))
+	return b.Bytes()
+}
+
+func TestGithubResolverVerifiesDigest(t *testing.T) {
+	content := testTgz(t)
+	digest := fmt.Sprintf("%x", sha256.Sum256(content))
+	fakeGithubRelease(t, content)
+
+	newCoord := func(asset *model.LockedAsset) *model.LockedCoord {
+		return &model.LockedCoord{Server: "github.com", Owner: "owner", Repo: "repo", Version: model.NewVersion("1.0.0"), Asset: asset}
+	}
+
+	// mismatch: nothing is extracted
+	appCtx := &model.AppContext{UserCacheDirName: t.TempDir()}
+	lc := newCoord(&model.LockedAsset{Name: "repo-v1.0.0.tgz", Sha256: "bad"})
+	_, err, _ := NewGithubResolver(appCtx).DownloadResolvedCoord(lc)
+	assert.ErrorContains(t, err, "sha256 mismatch")
+	versionDir := filepath.Join(appCtx.UserCacheDirName, "deps", "github.com", "owner", "repo", "v1.0.0")
+	assert.NoDirExists(t, filepath.Join(versionDir, "extracted"))
+	assert.NoFileExists(t, filepath.Join(versionDir, "repo-v1.0.0.tgz.tmp"))
+
+	// no digest in the lock: recorded in the coord and in the cache
+	lc = newCoord(nil)
+	dir, err, ok := NewGithubResolver(appCtx).DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.FileExists(t, filepath.Join(dir, ".bz.lock"))
+	assert.Equal(t, &model.LockedAsset{Name: "repo-v1.0.0.tgz", URL: lc.Asset.URL, Size: int64(len(content)), Sha256: digest}, lc.Asset)
+	cached, err := ReadAssetMetadata(versionDir)
+	assert.NoError(t, err)
+	assert.Equal(t, lc.Asset, cached)
+
+	// cached: checked against the lock without downloading
+	lc = newCoord(&model.LockedAsset{Name: "repo-v1.0.0.tgz", Sha256: digest})
+	_, err, _ = NewGithubResolver(appCtx).DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+	lc = newCoord(&model.LockedAsset{Name: "repo-v1.0.0.tgz", Sha256: "bad"})
+	_, err, _ = NewGithubResolver(appCtx).DownloadResolvedCoord(lc)
+	assert.ErrorContains(t, err, "sha256 mismatch")
+}
+
+func TestGithubResolverResolveCoordRecordsAsset(t *testing.T) {
+	content := testTgz(t)
+	fakeGithubRelease(t, content)
+
+	fc, _ := model.NewCoordFromStr("github.com/owner/repo@1.0.0")
+	lc, err := NewGithubResolver(&model.AppContext{}).ResolveCoord(fc)
+	assert.NoError(t, err)
+	assert.Equal(t, "repo-v1.0.0.tgz", lc.Asset.Name)
+	assert.Equal(t, int64(len(content)), lc.Asset.Size)
+	assert.Contains(t, lc.Asset.URL, "/download/repo-v1.0.0.tgz")
+	assert.Empty(t, lc.Asset.Sha256)
+}
+
+func TestGithubResolverLockPlatforms(t *testing.T) {
+	downloaded := make(map[string]int)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asset := func(id int, name string) map[string]interface{} {
+			return map[string]interface{}{"id": id, "name": name, "size": 10, "browser_download_url": server.URL + "/download/" + name}
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo/releases/tags/v1.0.0":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":   1,
+				"name": "v1.0.0",
+				"assets": []map[string]interface{}{
+					asset(2, "repo-v1.0.0.tgz"),
+					asset(3, "repo-linux-amd64-v1.0.0.tgz"),
+					asset(4, "repo-darwin-arm64-v1.0.0.tgz"),
+					asset(5, "checksums.txt"),
+				},
+			})
+		case "/repos/owner/repo/releases/assets/5":
+			downloaded["checksums.txt"]++
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprintln(w, "aaa  repo-linux-amd64-v1.0.0.tgz")
+			fmt.Fprintln(w, "bbb  repo-darwin-arm64-v1.0.0.tgz")
+			fmt.Fprintln(w, "ccc  repo-v1.0.0.tgz")
+		default:
+			downloaded[r.URL.Path]++
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	githubClientMap["github.com"] = client
+	t.Cleanup(func() { delete(githubClientMap, "github.com") })
+
+	lc := &model.LockedCoord{Server: "github.com", Owner: "owner", Repo: "repo", Version: model.NewVersion("1.0.0")}
+	platforms := []model.Platform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}, {OS: "windows", Arch: "amd64"}}
+	handled, err := NewGithubResolver(&model.AppContext{}).LockPlatforms(lc, platforms)
+	assert.True(t, handled)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"checksums.txt": 1}, downloaded)
+
+	// platform specific assets are preferred, windows falls back to the asset for every platform
+	assert.Len(t, lc.Platforms, 3)
+	assert.Equal(t, "repo-linux-amd64-v1.0.0.tgz", lc.Platforms["linux-amd64"].Name)
+	assert.Equal(t, "aaa", lc.Platforms["linux-amd64"].Sha256)
+	assert.Equal(t, "bbb", lc.Platforms["darwin-arm64"].Sha256)
+	assert.Equal(t, "repo-v1.0.0.tgz", lc.Platforms["windows-amd64"].Name)
+	assert.Equal(t, "ccc", lc.Platforms["windows-amd64"].Sha256)
+
+	// already locked: no requests, platforms no longer requested are removed
+	downloaded = make(map[string]int)
+	_, err = NewGithubResolver(&model.AppContext{Offline: true}).LockPlatforms(lc, platforms[:1])
+	assert.NoError(t, err)
+	assert.Empty(t, downloaded)
+	assert.Len(t, lc.Platforms, 1)
+
+	_, err = NewGithubResolver(&model.AppContext{Offline: true}).LockPlatforms(lc, platforms)
+	assert.ErrorContains(t, err, "offline")
+
+	// verified against the lock entry of the current platform
+	current := model.CurrentPlatform().String()
+	lc.Asset = &model.LockedAsset{Name: "repo-linux-amd64-v1.0.0.tgz", Sha256: "aaa"}
+	lc.Platforms = map[string]*model.LockedAsset{current: {Name: "repo-other-v1.0.0.tgz", Sha256: "ddd"}}
+	appCtx := &model.AppContext{}
+	assert.NoError(t, verifyAsset(appCtx, lc, &model.LockedAsset{Name: "repo-other-v1.0.0.tgz", Sha256: "ddd"}))
+	assert.ErrorContains(t, verifyAsset(appCtx, lc, &model.LockedAsset{Name: "repo-other-v1.0.0.tgz", Sha256: "eee"}), "sha256 mismatch")
+
+	// the current platform is not locked: cannot be verified, an error in frozen mode
+	lc.Platforms = nil
+	assert.NoError(t, verifyAsset(appCtx, lc, &model.LockedAsset{Name: "repo-other-v1.0.0.tgz", Sha256: "ddd"}))
+	appCtx.Frozen = true
+	err = verifyAsset(appCtx, lc, &model.LockedAsset{Name: "repo-other-v1.0.0.tgz", Sha256: "ddd"})
+	assert.ErrorContains(t, err, "bz :lock --platform "+model.CurrentPlatform().OS+"/"+model.CurrentPlatform().Arch)
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// forgeTimeout bounds the connection to a server and the wait for its
+// response headers.  The body is not bounded, assets are large downloads
+const forgeTimeout = 60 * time.Second
+
+// maxForgeRedirects is the number of redirects followed by a request
+const maxForgeRedirects = 10
+
+var forgeTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: forgeTimeout, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = forgeTimeout
+	t.ResponseHeaderTimeout = forgeTimeout
+	return t
+}()
+
+// forgeClient sends requests to the REST API of a GitLab or Gitea
+// server, or to an http server.  The token is only sent to the host of the
+// server and of its API, asset links can point anywhere
+type forgeClient struct {
+	server string
+	api    string // API base url
+	header string // authentication header
+	value  string // value of header, empty without token
+}
+
+// request sends a GET request to path of the API
+func (o *forgeClient) request(path string) (*http.Response, error) {
+	return o.do(http.MethodGet, strings.TrimSuffix(o.api, "/")+"/"+path)
+}
+
+// get decodes the json at path of the API into v.  False when not found
+func (o *forgeClient) get(path string, v interface{}) (bool, error) {
+	resp, err := o.request(path)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	defer resp.Body.Close()
+	return true, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// open downloads the asset at u
+func (o *forgeClient) open(u string) (io.ReadCloser, error) {
+	resp, err := o.do(http.MethodGet, u)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// exists returns true if there is a file at u.  Servers that refuse HEAD
+// requests, e.g. presigned urls of buckets, are asked for the first byte.
+// Buckets answer 403 for missing files
+func (o *forgeClient) exists(u string) (bool, error) {
+	resp, err := o.do(http.MethodHead, u)
+	if err != nil && resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusMethodNotAllowed) {
+		req, rerr := http.NewRequest(http.MethodGet, u, nil)
+		if rerr != nil {
+			return false, rerr
+		}
+		req.Header.Set("Range", "bytes=0-0")
+		resp, err = o.send(req)
+	}
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+func (o *forgeClient) do(method, u string) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return o.send(req)
+}
+
+// send sends req, with the token when it is for the server.  Responses
+// other than 200, or 206 for ranged requests, are errors
+func (o *forgeClient) send(req *http.Request) (*http.Response, error) {
+	if o.value != "" && o.isServerHost(req.URL.Host) {
+		req.Header.Set(o.header, o.value)
+	}
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusPartialContent && req.Header.Get("Range") != "") {
+		resp.Body.Close()
+		return resp, fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	return resp, nil
+}
+
+// httpClient returns the client sending the requests of o.  Redirects to
+// another host, e.g. an asset stored in a bucket, do not get the
+// authentication header
+func (o *forgeClient) httpClient() *http.Client {
+	return &http.Client{
+		Transport: forgeTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxForgeRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxForgeRedirects)
+			}
+			if !o.isServerHost(req.URL.Host) {
+				req.Header.Del(o.header)
+			}
+			return nil
+		},
+	}
+}
+
+func (o *forgeClient) isServerHost(host string) bool {
+	if strings.EqualFold(host, o.server) {
+		return true
+	}
+	api, err := url.Parse(o.api)
+	return err == nil && strings.EqualFold(host, api.Host)
+}
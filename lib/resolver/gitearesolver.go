@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+)
+
+// GiteaResolver resolves the dependencies of the servers with type "gitea"
+// in the user configuration (Gitea and Forgejo) from the assets of releases
+type GiteaResolver struct {
+	appCtx *model.AppContext
+}
+
+func NewGiteaResolver(appCtx *model.AppContext) *GiteaResolver {
+	return &GiteaResolver{appCtx}
+}
+
+func (o *GiteaResolver) String() string {
+	return "GiteaResolver{}"
+}
+
+// giteaRelease is a release returned by the Gitea releases API
+type giteaRelease struct {
+	Name       string       `json:"name"`
+	TagName    string       `json:"tag_name"`
+	Draft      bool         `json:"draft"`
+	Prerelease bool         `json:"prerelease"`
+	Assets     []giteaAsset `json:"assets"`
+}
+
+type giteaAsset struct {
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// version returns the version of the release, from the tag
+func (o *giteaRelease) version() model.Version {
+	if o.TagName != "" {
+		return model.NewVersion(o.TagName)
+	}
+	return model.NewVersion(o.Name)
+}
+
+func (o *GiteaResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord, error) {
+	Debug.Printf("Start GiteaResolver.ResolveCoord(%s)", c)
+
+	// if not gitea, then bail out
+	if !IsGiteaServer(o.appCtx, c.Server) {
+		return nil, nil
+	}
+
+	if o.appCtx.Offline {
+		return nil, fmt.Errorf("GiteaResolver.ResolveCoord(%s): cannot resolve in offline mode", c)
+	}
+
+	release, err := resolveRelease(o.gtForge(c.Server), c)
+	if err != nil {
+		return nil, fmt.Errorf("GiteaResolver.ResolveCoord(): %w", err)
+	}
+	return lockedRelease(c, release), nil
+}
+
+func (o *GiteaResolver) DownloadResolvedCoord(lc *model.LockedCoord) (string, error, bool) {
+	Debug.Printf("Start GiteaResolver.DownloadResolvedCoord(%v)", lc)
+
+	// if not gitea, then bail out
+	if !IsGiteaServer(o.appCtx, lc.Server) {
+		return "", nil, false
+	}
+
+	dir, err := downloadRelease(o.appCtx, o.gtForge(lc.Server), lc)
+	if err != nil {
+		return "", fmt.Errorf("GiteaResolver.DownloadResolvedCoord(): %w", err), false
+	}
+	return dir, nil, true
+}
+
+// ListVersions implements VersionLister
+func (o *GiteaResolver) ListVersions(c *model.FuzzyCoord) ([]model.Version, bool, error) {
+	if !IsGiteaServer(o.appCtx, c.Server) {
+		return nil, false, nil
+	}
+	if o.appCtx.Offline {
+		return nil, true, fmt.Errorf("GiteaResolver.ListVersions(%s): cannot list versions in offline mode", c)
+	}
+
+	versions, err := releaseVersions(o.gtForge(c.Server), c)
+	if err != nil {
+		return nil, true, fmt.Errorf("GiteaResolver.ListVersions(): %w", err)
+	}
+	return versions, true, nil
+}
+
+// LockPlatforms implements PlatformLocker.  Digests are read from the
+// checksums asset of the release, other assets are not downloaded
+func (o *GiteaResolver) LockPlatforms(lc *model.LockedCoord, platforms []model.Platform) (bool, error) {
+	if !IsGiteaServer(o.appCtx, lc.Server) {
+		return false, nil
+	}
+
+	if err := lockReleasePlatforms(o.appCtx, o.gtForge(lc.Server), lc, platforms); err != nil {
+		return true, fmt.Errorf("GiteaResolver.LockPlatforms(%s): %w", lc, err)
+	}
+	return true, nil
+}
+
+// giteaForge is the releaseForge of a Gitea server
+type giteaForge struct {
+	client *forgeClient
+}
+
+// gtForge returns the releaseForge of server
+func (o *GiteaResolver) gtForge(server string) *giteaForge {
+	return &giteaForge{client: o.gtClient(server)}
+}
+
+// forgeRelease converts a release of the API
+func (o *giteaRelease) forgeRelease() *forgeRelease {
+	release := &forgeRelease{version: o.version(), prerelease: o.Prerelease}
+	for _, a := range o.Assets {
+		release.assets = append(release.assets, &forgeAsset{name: a.Name, url: a.BrowserDownloadURL, size: a.Size})
+	}
+	return release
+}
+
+func (o *giteaForge) getRelease(owner, repo, tag string) (*forgeRelease, error) {
+	var release giteaRelease
+	found, err := o.client.get(fmt.Sprintf("%s/releases/tags/%s", gtRepoPath(owner, repo), url.PathEscape(tag)), &release)
+	if err != nil || !found {
+		return nil, err
+	}
+	return release.forgeRelease(), nil
+}
+
+func (o *giteaForge) open(owner, repo string, asset *forgeAsset) (io.ReadCloser, error) {
+	return o.client.open(asset.url)
+}
+
+// listReleases returns all releases of a repository except drafts
+func (o *giteaForge) listReleases(owner, repo string) ([]*forgeRelease, error) {
+	limit := 30
+	page := 1
+	var all []*forgeRelease
+
+	for {
+		Debug.Printf(" | call listReleases %s/%s/%d/%d", owner, repo, page, limit)
+		var releases []*giteaRelease
+		resp, err := o.client.request(fmt.Sprintf("%s/releases?page=%d&limit=%d", gtRepoPath(owner, repo), page, limit))
+		if err != nil {
+			return nil, fmt.Errorf("listReleases(): %w", err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&releases)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listReleases(): %w", err)
+		}
+		for _, release := range releases {
+			if release.Draft {
+				continue
+			}
+			all = append(all, release.forgeRelease())
+		}
+
+		// the Link header has the next page, the server can lower limit
+		if link := resp.Header.Get("Link"); len(releases) == 0 || (link != "" && !strings.Contains(link, `rel="next"`)) {
+			break
+		} else if link == "" && len(releases) < limit {
+			break
+		}
+		page++
+	}
+	return all, nil
+}
+
+// gtClient returns the client of the API of server, authenticated with the
+// token of the user configuration
+func (o *GiteaResolver) gtClient(server string) *forgeClient {
+	client := &forgeClient{
+		server: server,
+		api:    GiteaAPI(o.appCtx, server),
+		header: "Authorization",
+	}
+	if token := o.appCtx.UserConfig.GetServerToken(server); token != "" {
+		client.value = "token " + token
+	}
+	return client
+}
+
+func gtRepoPath(owner, repo string) string {
+	return "repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// IsGiteaServer returns true if the dependencies of server are resolved with
+// the Gitea API: servers with type "gitea" in the user configuration
+func IsGiteaServer(appCtx *model.AppContext, server string) bool {
+	return appCtx.UserConfig.GetServerType(server) == model.ServerTypeGitea
+}
+
+// GiteaAPI returns the API url of server, https://{server}/api/v1 unless
+// configured in the user configuration
+func GiteaAPI(appCtx *model.AppContext, server string) string {
+	if cfg := appCtx.UserConfig.GetServer(server); cfg != nil && cfg.API != "" {
+		return cfg.API
+	}
+	return fmt.Sprintf("https://%s/api/v1", server)
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGiteaResolver(t *testing.T) {
+	assets := conformanceAssets(t)
+
+	// assets are stored on another host, the token must not leak to it
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			http.Error(w, "unexpected token", http.StatusBadRequest)
+			return
+		}
+		for _, a := range assets {
+			if strings.HasSuffix(r.URL.Path, "/"+a.name) {
+				w.Write(a.content)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(storage.Close)
+
+	// hidden releases are drafts, the Link header has the next page
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			http.Error(w, `{"message": "token is required"}`, http.StatusUnauthorized)
+			return
+		}
+		release := func(c conformanceRelease) map[string]interface{} {
+			var list []map[string]interface{}
+			for _, a := range assets {
+				list = append(list, map[string]interface{}{
+					"name": a.name, "size": len(a.content), "browser_download_url": storage.URL + "/" + c.tag + "/" + a.name,
+				})
+			}
+			return map[string]interface{}{"name": c.tag, "tag_name": c.tag, "draft": c.hidden, "prerelease": c.prerelease, "assets": list}
+		}
+		releases := "/api/v1/repos/owner/repo/releases"
+		tag := conformanceTag(strings.TrimPrefix(r.URL.Path, releases+"/tags/"))
+		switch {
+		case r.URL.Path == releases:
+			page, next := conformancePage(r)
+			if next != 0 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d&limit=30>; rel="next"`, server.URL, releases, next))
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=1&limit=30>; rel="first"`, server.URL, releases))
+			}
+			var list []interface{}
+			for _, c := range page {
+				list = append(list, release(c))
+			}
+			json.NewEncoder(w).Encode(list)
+		case strings.HasPrefix(r.URL.Path, releases+"/tags/") && tag != nil:
+			json.NewEncoder(w).Encode(release(*tag))
+		default:
+			http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	appCtx := &model.AppContext{
+		UserCacheDirName: t.TempDir(),
+		UserConfig: model.UserConfig{Servers: []model.UserConfigServer{
+			{Name: "git.example.org", Type: "gitea", API: server.URL + "/api/v1", Token: "secret"},
+		}},
+	}
+	testReleaseResolver(t, NewGiteaResolver(appCtx), appCtx, "git.example.org", true)
+
+	assert.False(t, IsGiteaServer(appCtx, "github.com"))
+	assert.Equal(t, "https://git.other.org/api/v1", GiteaAPI(appCtx, "git.other.org"))
+}
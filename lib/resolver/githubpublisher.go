@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/google/go-github/v47/github"
+)
+
+// PublishedAsset is a file uploaded to a release
+type PublishedAsset struct {
+	Name     string
+	URL      string // browser download url
+	Size     int64
+	Replaced bool // an asset with the same name existed and was replaced
+}
+
+// GithubPublisher uploads release assets to GitHub or a server with a
+// compatible API
+type GithubPublisher struct {
+	appCtx *model.AppContext
+	client *github.Client
+}
+
+// NewGithubPublisher creates a publisher for server using the token, api and
+// uploads urls of server in the user configuration.  apiURL overrides the API
+// base url, uploads are then sent to the uploads url derived from it like for
+// servers without uploads in the user configuration
+func NewGithubPublisher(appCtx *model.AppContext, server, apiURL string) (*GithubPublisher, error) {
+	if apiURL != "" {
+		client, err := github.NewEnterpriseClient(apiURL, githubUploadsURL(apiURL), newGithubHttpClient(appCtx, server))
+		if err != nil {
+			return nil, fmt.Errorf("NewGithubPublisher(): %w", err)
+		}
+		return &GithubPublisher{appCtx: appCtx, client: client}, nil
+	}
+	client, err := newGithubServerClient(appCtx, server)
+	if err != nil {
+		return nil, fmt.Errorf("NewGithubPublisher(): %w", err)
+	}
+	return &GithubPublisher{appCtx: appCtx, client: client}, nil
+}
+
+func (o *GithubPublisher) String() string {
+	return "GithubPublisher{}"
+}
+
+// Publish uploads files to the release tag of owner/repo creating the release
+// when it does not exist.  Nothing is uploaded if an asset with the name of
+// one of the files already exists, unless force is true in which case the
+// existing asset is replaced once the new one is uploaded
+func (o *GithubPublisher) Publish(owner, repo, tag string, files []string, force bool) ([]*PublishedAsset, error) {
+	Debug.Printf("Start GithubPublisher.Publish(%s, %s, %s)", owner, repo, tag)
+	ctx := context.Background()
+
+	release, err := o.getOrCreateRelease(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("GithubPublisher.Publish(): %w", err)
+	}
+
+	existing, err := o.listAssets(ctx, owner, repo, release.GetID())
+	if err != nil {
+		return nil, fmt.Errorf("GithubPublisher.Publish(): %w", err)
+	}
+
+	if !force {
+		var conflicts []string
+		for _, f := range files {
+			if _, ok := existing[filepath.Base(f)]; ok {
+				conflicts = append(conflicts, filepath.Base(f))
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, fmt.Errorf(
+				"release %s of %s/%s already has %s (use --force to replace)",
+				tag, owner, repo, strings.Join(conflicts, ", "),
+			)
+		}
+	}
+
+	var published []*PublishedAsset
+	for _, f := range files {
+		name := filepath.Base(f)
+		var asset *github.ReleaseAsset
+		old, replaced := existing[name]
+		if replaced {
+			asset, err = o.replaceAsset(ctx, owner, repo, release.GetID(), f, old, existing)
+		} else {
+			asset, err = o.uploadAsset(ctx, owner, repo, release.GetID(), f, name)
+		}
+		if err != nil {
+			return published, fmt.Errorf("GithubPublisher.Publish(%s): %w", name, err)
+		}
+		published = append(published, &PublishedAsset{
+			Name:     asset.GetName(),
+			URL:      asset.GetBrowserDownloadURL(),
+			Size:     int64(asset.GetSize()),
+			Replaced: replaced,
+		})
+	}
+	return published, nil
+}
+
+// replaceAsset uploads file under a temporary name, deletes old and renames
+// the new asset, so old is only deleted once the upload succeeded
+func (o *GithubPublisher) replaceAsset(
+	ctx context.Context,
+	owner, repo string,
+	id int64,
+	file string,
+	old *github.ReleaseAsset,
+	existing map[string]*github.ReleaseAsset,
+) (*github.ReleaseAsset, error) {
+	name := old.GetName()
+	tmpName := name + ".uploading"
+	if leftover, ok := existing[tmpName]; ok {
+		Debug.Printf(" | call client.Repositories.DeleteReleaseAsset(%s, %s, %d)", owner, repo, leftover.GetID())
+		if _, err := o.client.Repositories.DeleteReleaseAsset(ctx, owner, repo, leftover.GetID()); err != nil {
+			return nil, err
+		}
+	}
+
+	asset, err := o.uploadAsset(ctx, owner, repo, id, file, tmpName)
+	if err != nil {
+		return nil, fmt.Errorf("%w (the existing asset was not modified)", err)
+	}
+
+	Debug.Printf(" | call client.Repositories.DeleteReleaseAsset(%s, %s, %d)", owner, repo, old.GetID())
+	if _, err := o.client.Repositories.DeleteReleaseAsset(ctx, owner, repo, old.GetID()); err != nil {
+		return nil, fmt.Errorf("unable to delete the existing asset, the new one was uploaded as %s: %w", tmpName, err)
+	}
+
+	Debug.Printf(" | call client.Repositories.EditReleaseAsset(%s, %s, %d, %s)", owner, repo, asset.GetID(), name)
+	renamed, _, err := o.client.Repositories.EditReleaseAsset(ctx, owner, repo, asset.GetID(), &github.ReleaseAsset{Name: github.String(name)})
+	if err != nil {
+		return nil, fmt.Errorf("the existing asset was deleted but the new one could not be renamed from %s: %w", tmpName, err)
+	}
+	return renamed, nil
+}
+
+// getOrCreateRelease returns the release of tag.  The release is created,
+// named after the tag and with generated notes, when it does not exist
+func (o *GithubPublisher) getOrCreateRelease(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	Debug.Printf(" | call client.Repositories.GetReleaseByTag(%s, %s, %s)", owner, repo, tag)
+	release, r, err := o.client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err == nil {
+		return release, nil
+	}
+	if r == nil || r.StatusCode != http.StatusNotFound {
+		return nil, err
+	}
+
+	Debug.Printf(" | call client.Repositories.CreateRelease(%s, %s, %s)", owner, repo, tag)
+	release, _, err = o.client.Repositories.CreateRelease(ctx, owner, repo, &github.RepositoryRelease{
+		TagName:              github.String(tag),
+		Name:                 github.String(tag),
+		GenerateReleaseNotes: github.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	Info.Printf("Created release %s of %s/%s", tag, owner, repo)
+	return release, nil
+}
+
+// listAssets returns asset name => asset of the release id
+func (o *GithubPublisher) listAssets(ctx context.Context, owner, repo string, id int64) (map[string]*github.ReleaseAsset, error) {
+	assets := make(map[string]*github.ReleaseAsset)
+	opts := &github.ListOptions{Page: 1, PerPage: 100}
+	for {
+		Debug.Printf(" | call client.Repositories.ListReleaseAssets(%s, %s, %d, %d)", owner, repo, id, opts.Page)
+		page, resp, err := o.client.Repositories.ListReleaseAssets(ctx, owner, repo, id, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range page {
+			assets[a.GetName()] = a
+		}
+		if resp.NextPage == 0 {
+			return assets, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// uploadAsset uploads file to the release id as name
+func (o *GithubPublisher) uploadAsset(ctx context.Context, owner, repo string, id int64, file, name string) (*github.ReleaseAsset, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	Info.Printf("Uploading file %s ...", name)
+	asset, _, err := o.client.Repositories.UploadReleaseAsset(ctx, owner, repo, id, &github.UploadOptions{Name: name}, f)
+	if err != nil {
+		return nil, err
+	}
+	Info.Printf("Uploading file %s DONE", name)
+	return asset, nil
+}
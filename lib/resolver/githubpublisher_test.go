@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeReleaseServer implements the release endpoints used by GithubPublisher
+type fakeReleaseServer struct {
+	releases   map[string]int64  // tag => id
+	assets     map[string][]byte // name => content
+	ids        map[int64]string  // asset id => name
+	nextID     int64
+	failUpload bool     // uploads fail with 500
+	uploads    []string // paths of the upload requests
+}
+
+func newFakeReleaseServer(t *testing.T) (*fakeReleaseServer, *httptest.Server) {
+	fake := &fakeReleaseServer{
+		releases: make(map[string]int64),
+		assets:   make(map[string][]byte),
+		ids:      make(map[int64]string),
+		nextID:   1,
+	}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeReleaseServer) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+	path = strings.TrimPrefix(path, "/api/uploads")
+	path = strings.TrimPrefix(path, "/repos/owner/repo/releases")
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/tags/"):
+		id, ok := f.releases[strings.TrimPrefix(path, "/tags/")]
+		if !ok {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id})
+	case r.Method == http.MethodPost && path == "":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := f.nextID
+		f.nextID++
+		f.releases[body["tag_name"].(string)] = id
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "tag_name": body["tag_name"], "name": body["name"]})
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/assets"):
+		var list []map[string]interface{}
+		for id, name := range f.ids {
+			list = append(list, map[string]interface{}{"id": id, "name": name})
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/assets") && f.failUpload:
+		f.uploads = append(f.uploads, r.URL.Path)
+		http.Error(w, `{"message": "upload failed"}`, http.StatusInternalServerError)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/assets"):
+		f.uploads = append(f.uploads, r.URL.Path)
+		b, _ := io.ReadAll(r.Body)
+		name := r.URL.Query().Get("name")
+		id := f.nextID
+		f.nextID++
+		f.assets[name] = b
+		f.ids[id] = name
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                   id,
+			"name":                 name,
+			"size":                 len(b),
+			"browser_download_url": fmt.Sprintf("https://example.com/%s", name),
+		})
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/assets/"):
+		var id int64
+		fmt.Sscanf(strings.TrimPrefix(path, "/assets/"), "%d", &id)
+		delete(f.assets, f.ids[id])
+		delete(f.ids, id)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/assets/"):
+		var id int64
+		fmt.Sscanf(strings.TrimPrefix(path, "/assets/"), "%d", &id)
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		name := body["name"].(string)
+		f.assets[name] = f.assets[f.ids[id]]
+		delete(f.assets, f.ids[id])
+		f.ids[id] = name
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                   id,
+			"name":                 name,
+			"size":                 len(f.assets[name]),
+			"browser_download_url": fmt.Sprintf("https://example.com/%s", name),
+		})
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestGithubPublisherPublish(t *testing.T) {
+	fake, server := newFakeReleaseServer(t)
+	appCtx := &model.AppContext{}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "repo-v1.0.0.tgz")
+	assert.NoError(t, os.WriteFile(file, []byte("first"), 0644))
+
+	publisher, err := NewGithubPublisher(appCtx, "github.com", server.URL)
+	assert.NoError(t, err)
+
+	// creates the release
+	published, err := publisher.Publish("owner", "repo", "v1.0.0", []string{file}, false)
+	assert.NoError(t, err)
+	assert.Len(t, published, 1)
+	assert.Equal(t, "repo-v1.0.0.tgz", published[0].Name)
+	assert.Equal(t, int64(5), published[0].Size)
+	assert.False(t, published[0].Replaced)
+	assert.Contains(t, fake.releases, "v1.0.0")
+	assert.Equal(t, []byte("first"), fake.assets["repo-v1.0.0.tgz"])
+
+	// uploads go to the uploads url derived from --api-url
+	assert.Equal(t, server.URL+"/api/v3/", publisher.client.BaseURL.String())
+	assert.Equal(t, []string{"/api/uploads/repos/owner/repo/releases/1/assets"}, fake.uploads)
+	enterprise, err := NewGithubPublisher(appCtx, "github.com", server.URL+"/api/v3")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/api/v3/", enterprise.client.BaseURL.String())
+	assert.Equal(t, server.URL+"/api/uploads/", enterprise.client.UploadURL.String())
+
+	// refuses to overwrite
+	assert.NoError(t, os.WriteFile(file, []byte("second"), 0644))
+	_, err = publisher.Publish("owner", "repo", "v1.0.0", []string{file}, false)
+	assert.ErrorContains(t, err, "already has repo-v1.0.0.tgz")
+	assert.Equal(t, []byte("first"), fake.assets["repo-v1.0.0.tgz"])
+	assert.Len(t, fake.releases, 1)
+
+	// the existing asset is kept when the upload fails
+	fake.failUpload = true
+	_, err = publisher.Publish("owner", "repo", "v1.0.0", []string{file}, true)
+	assert.ErrorContains(t, err, "not modified")
+	assert.Equal(t, []byte("first"), fake.assets["repo-v1.0.0.tgz"])
+	fake.failUpload = false
+
+	// replaces with force
+	published, err = publisher.Publish("owner", "repo", "v1.0.0", []string{file}, true)
+	assert.NoError(t, err)
+	assert.True(t, published[0].Replaced)
+	assert.Equal(t, "repo-v1.0.0.tgz", published[0].Name)
+	assert.Equal(t, int64(6), published[0].Size)
+	assert.Equal(t, map[string][]byte{"repo-v1.0.0.tgz": []byte("second")}, fake.assets)
+	assert.Len(t, fake.ids, 1)
+}
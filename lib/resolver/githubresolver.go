@@ -8,12 +8,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strings"
 
 	"github.com/bazurto/bz/lib/model"
-	"github.com/bazurto/bz/lib/utils"
 	"github.com/google/go-github/v47/github"
 
 	"golang.org/x/oauth2"
@@ -39,237 +37,255 @@ func (o *GithubResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord,
 	Debug.Printf("Start GithubResolver.ResolveCoord(%s)", c)
 
 	// if not github, then bail out
-	if c.Server != "github.com" {
+	if !IsGithubServer(o.appCtx, c.Server) {
 		Debug.Printf("Start GithubResolver.ResolveCoord(%s): not a github dependency...", c)
 		return nil, nil
 	}
 
+	if o.appCtx.Offline {
+		return nil, fmt.Errorf("GithubResolver.ResolveCoord(%s): cannot resolve in offline mode", c)
+	}
+
 	//
-	ctx := context.Background()
-	client := o.newGithubClient(c.Server)
+	f, err := o.ghForge(c.Server)
+	if err != nil {
+		return nil, fmt.Errorf("GithubResolver.ResolveCoord(): %w", err)
+	}
 
 	//
 	// query github
 	// latest if set to latest vLATEST
 	// resolve for precise tag v1.2.3.4 -> v1.2.3.4
 	// resolve for v1.2 -> v.1.2.3.4
-	var release *github.RepositoryRelease
-	var err error
-	if c.Version == "" || c.Version == "0" {
-		Debug.Printf(" | call client.Repositories.GetLatestRelease(%s, %s)", c.Owner, c.Repo)
-		release, _, err = client.Repositories.GetLatestRelease(ctx, c.Owner, c.Repo)
-	} else {
-		// try to get exact tag
-		var r *github.Response
-		Debug.Printf(" | call client.Repositories.GetReleaseByTag (%s, %s, %s)", c.Owner, c.Repo, fmt.Sprintf("v%s", c.Version))
-		release, r, err = client.Repositories.GetReleaseByTag(ctx, c.Owner, c.Repo, fmt.Sprintf("v%s", c.Version))
-		if err != nil && r.StatusCode == http.StatusNotFound {
-			release, err = o.ghFindReleaseByPattern(client, c.Owner, c.Repo, fmt.Sprintf("v%s", c.Version))
+	// resolve for ^1.4, >=1.2 <2 -> highest matching release
+	var release *forgeRelease
+	constraint := c.Constraint()
+	if (c.Version == "" || c.Version == "0") && !constraint.Prerelease() {
+		// the latest release is never a pre-release
+		release, err = f.latestRelease(c.Owner, c.Repo)
+		if err == nil && !constraint.Matches(release.version) {
+			// tagged as a pre-release (v3.1.0-rc1) without marking it as such
+			release, err = findRelease(f, c.Owner, c.Repo, constraint)
 		}
+	} else {
+		release, err = resolveRelease(f, c)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("GithubResolver.ResolveCoord(): %w", err)
 	}
-
-	//
-	version := model.NewVersion(release.GetName())
-	if err != nil {
-		return nil, fmt.Errorf("GithubResolver.ResolveCoord() NewVersion: %w", err)
-	}
-
-	return &model.LockedCoord{
-		Server:  c.Server,
-		Owner:   c.Owner,
-		Repo:    c.Repo,
-		Version: version,
-	}, nil
+	return lockedRelease(c, release), nil
 }
 
 func (o *GithubResolver) DownloadResolvedCoord(lc *model.LockedCoord) (string, error, bool) {
 	Debug.Printf("Start DownloadResolvedCoord(%v)", lc)
 
 	// if not github, then bail out
-	if lc.Server != "github.com" {
+	if !IsGithubServer(o.appCtx, lc.Server) {
 		return "", nil, false
 	}
 
-	dir := filepath.Join(
-		o.appCtx.UserCacheDirName,
-		"deps",
-		lc.Server,
-		lc.Owner,
-		lc.Repo,
-		fmt.Sprintf("v%s", lc.Version.Canonical()),
-	)
-	extractToDir := filepath.Join(dir, "extracted")
-
-	// nothing to do... already installed
-	if utils.FileExists(extractToDir) {
-		return extractToDir, nil, true
+	f, err := o.ghForge(lc.Server)
+	if err != nil {
+		return "", fmt.Errorf("GithubResolver.DownloadResolvedCoord(): %w", err), false
 	}
+	dir, err := downloadRelease(o.appCtx, f, lc)
+	if err != nil {
+		return "", fmt.Errorf("GithubResolver.DownloadResolvedCoord(): %w", err), false
+	}
+	return dir, nil, true
+}
 
-	//
-	ctx := context.Background()
-	client := o.newGithubClient(lc.Server)
+// LockPlatforms implements PlatformLocker.  Digests are read from the
+// checksums asset of the release, other assets are not downloaded
+func (o *GithubResolver) LockPlatforms(lc *model.LockedCoord, platforms []model.Platform) (bool, error) {
+	if !IsGithubServer(o.appCtx, lc.Server) {
+		return false, nil
+	}
 
-	//
-	githubVersion := fmt.Sprintf("v%s", lc.Version.Canonical())
-	release, _, err := client.Repositories.GetReleaseByTag(ctx, lc.Owner, lc.Repo, githubVersion)
+	f, err := o.ghForge(lc.Server)
 	if err != nil {
-		return "", err, false
+		return true, fmt.Errorf("GithubResolver.LockPlatforms(%s): %w", lc, err)
+	}
+	if err := lockReleasePlatforms(o.appCtx, f, lc, platforms); err != nil {
+		return true, fmt.Errorf("GithubResolver.LockPlatforms(%s): %w", lc, err)
 	}
+	return true, nil
+}
 
-	// Get the asset name that we should download in the priority order of possible asset names function
-	asset, err := o.getAssetFromRelease(lc, release)
-	if err != nil {
-		return "", fmt.Errorf("GithubResolver.DownloadResolvedCoord(): %w", err), false
+// ListVersions implements VersionLister
+func (o *GithubResolver) ListVersions(c *model.FuzzyCoord) ([]model.Version, bool, error) {
+	if !IsGithubServer(o.appCtx, c.Server) {
+		return nil, false, nil
+	}
+	if o.appCtx.Offline {
+		return nil, true, fmt.Errorf("GithubResolver.ListVersions(%s): cannot list versions in offline mode", c)
 	}
 
-	// download file
-	if err := utils.MkdirIfNotExists(dir); err != nil {
-		return "", err, false
-	} else {
-		Debug.Printf("dir already exists: %s", dir)
+	f, err := o.ghForge(c.Server)
+	if err != nil {
+		return nil, true, fmt.Errorf("GithubResolver.ListVersions(): %w", err)
 	}
+	versions, err := releaseVersions(f, c)
+	if err != nil {
+		return nil, true, fmt.Errorf("GithubResolver.ListVersions(): %w", err)
+	}
+	return versions, true, nil
+}
 
-	file := filepath.Join(dir, asset.GetName())
-	downloadFileTmp, err := func() (string, error) {
-		downloadFileTmp := fmt.Sprintf("%s.tmp", file)
-		w, err := os.Create(downloadFileTmp)
-		if err != nil {
-			return "", err
-		}
-		defer w.Close()
+// githubForge is the releaseForge of a GitHub server.  The version of a
+// release is its name
+type githubForge struct {
+	client *github.Client
+}
 
-		//
-		readCloser, _, err := client.Repositories.DownloadReleaseAsset(ctx, lc.Owner, lc.Repo, asset.GetID(), http.DefaultClient)
-		if err != nil {
-			return "", err
-		}
-		defer readCloser.Close()
-		Info.Printf("Downloading file %s ...", file)
-		if _, err := io.Copy(w, readCloser); err != nil {
-			return "", err
-		}
-		return downloadFileTmp, nil
-	}()
+// ghForge returns the releaseForge of server
+func (o *GithubResolver) ghForge(server string) (*githubForge, error) {
+	client, err := o.newGithubClient(server)
 	if err != nil {
-		return "", err, false
+		return nil, err
 	}
+	return &githubForge{client: client}, nil
+}
 
-	// rename tmp download file to downloadFile
-	if err := os.Rename(downloadFileTmp, file); err != nil {
-		return "", err, false
-	} else {
-		Info.Printf("Downloading file %s DONE", file)
+// githubRelease converts a release of the API
+func githubRelease(r *github.RepositoryRelease) *forgeRelease {
+	release := &forgeRelease{version: model.NewVersion(r.GetName()), prerelease: r.GetPrerelease()}
+	for _, a := range r.Assets {
+		release.assets = append(release.assets, &forgeAsset{
+			id:   a.GetID(),
+			name: a.GetName(),
+			url:  a.GetBrowserDownloadURL(),
+			size: int64(a.GetSize()),
+		})
 	}
+	return release
+}
 
-	err = o.extractDependency(lc, file, extractToDir)
+// latestRelease returns the latest release of owner/repo, never a
+// pre-release
+func (o *githubForge) latestRelease(owner, repo string) (*forgeRelease, error) {
+	Debug.Printf(" | call client.Repositories.GetLatestRelease(%s, %s)", owner, repo)
+	release, _, err := o.client.Repositories.GetLatestRelease(context.Background(), owner, repo)
 	if err != nil {
-		return "", fmt.Errorf("unable to extract dependency: %w", err), false
+		return nil, err
 	}
-
-	return extractToDir, nil, true
+	return githubRelease(release), nil
 }
 
-func (o *GithubResolver) getAssetFromRelease(c *model.LockedCoord, release *github.RepositoryRelease) (*github.ReleaseAsset, error) {
-	var asset *github.ReleaseAsset
-	expectedNames := possibleAssetNames(c)
-	for _, expected := range expectedNames {
-		for _, a := range release.Assets {
-			//Debug.Printf(" | is %s == %s", expected.NameWithExt(), a.GetName())
-			if expected.NameWithExt() == a.GetName() {
-				Debug.Printf("found asset : %s", a.GetName())
-				asset = a
-				break
-			}
+func (o *githubForge) getRelease(owner, repo, tag string) (*forgeRelease, error) {
+	Debug.Printf(" | call client.Repositories.GetReleaseByTag (%s, %s, %s)", owner, repo, tag)
+	release, r, err := o.client.Repositories.GetReleaseByTag(context.Background(), owner, repo, tag)
+	if err != nil {
+		if r != nil && r.StatusCode == http.StatusNotFound {
+			return nil, nil
 		}
+		return nil, err
 	}
-	if asset == nil {
-		return nil, fmt.Errorf(
-			"could not find asset %s in depedency [%s]",
-			strings.Join(BzAssetArrHelper(expectedNames).CollectNames(), ","),
-			c.String(),
-		)
-	}
-	return asset, nil
+	return githubRelease(release), nil
 }
 
-func (o *GithubResolver) ghFindReleaseByPattern(client *github.Client, owner, repo, patternStr string) (*github.RepositoryRelease, error) {
-	Debug.Printf("ghFindReleaseByPattern(%s, %s, %s)", owner, repo, patternStr)
-	// if not found search for it
+func (o *githubForge) open(owner, repo string, asset *forgeAsset) (io.ReadCloser, error) {
+	Debug.Printf(" | call client.Repositories.DownloadReleaseAsset(%s, %s, %s)", owner, repo, asset.name)
+	readCloser, _, err := o.client.Repositories.DownloadReleaseAsset(context.Background(), owner, repo, asset.id, http.DefaultClient)
+	return readCloser, err
+}
+
+// listReleases returns all releases of a repository except drafts
+func (o *githubForge) listReleases(owner, repo string) ([]*forgeRelease, error) {
 	perPage := 30
 	page := 1
-	var latest *github.RepositoryRelease
+	var all []*forgeRelease
 	var releases []*github.RepositoryRelease
 	var resp *github.Response
 	var err error
 
-	pattern := model.NewVersionPattern(patternStr)
-
 	for resp == nil || resp.NextPage != 0 {
 		Debug.Printf(" | call client.Repositories.ListReleases %s/%s/%d/%d", owner, repo, page, perPage)
-		releases, resp, err = client.Repositories.ListReleases(
+		releases, resp, err = o.client.Repositories.ListReleases(
 			context.Background(),
 			owner,
 			repo,
 			&github.ListOptions{Page: page, PerPage: perPage},
 		)
 		if err != nil {
-			return nil, fmt.Errorf("ghFindReleaseByPattern(): %w", err)
+			return nil, fmt.Errorf("listReleases(): %w", err)
 		}
 		for _, release := range releases {
 			if release.GetDraft() {
 				continue
 			}
-			Debug.Printf(" || '%s'.matches(%s)", patternStr, release.GetName())
-			if pattern.Matches(model.NewVersion(release.GetName())) &&
-				(latest == nil || versionCompare(release.GetName(), latest.GetName()) > 1) {
-				Debug.Printf(" || found %s", release.GetName())
-				latest = release
-			}
+			all = append(all, githubRelease(release))
 		}
 
 		page = resp.NextPage
 	}
-
-	if latest != nil {
-		Debug.Println(" | returning ", latest.GetName())
-		return latest, nil
-	}
-	return nil, fmt.Errorf("dependency %s/%s-%s not found", owner, repo, patternStr)
+	return all, nil
 }
 
-func (o *GithubResolver) newGithubClient(server string) *github.Client {
+func (o *GithubResolver) newGithubClient(server string) (*github.Client, error) {
 	if client, ok := githubClientMap[server]; ok {
-		return client
+		return client, nil
 	}
 
-	// github client
-	ctx := context.Background()
-	githubAccessToken := o.appCtx.UserConfig.GetServerToken(server)
-	var tc *http.Client = nil
-	if githubAccessToken != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: githubAccessToken},
-		)
-		tc = oauth2.NewClient(ctx, ts)
+	client, err := newGithubServerClient(o.appCtx, server)
+	if err != nil {
+		return nil, err
 	}
-	client := github.NewClient(tc)
 	githubClientMap[server] = client
-	return client
+	return client, nil
 }
 
-func (o *GithubResolver) extractDependency(lc *model.LockedCoord, file string, extractToDir string) error {
-	var err error
-	ext := filepath.Ext(file)
-	if ext == ".zip" {
-		err = utils.Unzip(file, extractToDir)
-	} else if ext == ".tgz" {
-		err = utils.Untgz(file, extractToDir)
+// IsGithubServer returns true if the dependencies of server are resolved
+// with the GitHub API: github.com and servers with type "github" in the user
+// configuration (GitHub Enterprise Server)
+func IsGithubServer(appCtx *model.AppContext, server string) bool {
+	return server == "github.com" || appCtx.UserConfig.GetServerType(server) == model.ServerTypeGithub
+}
+
+// newGithubServerClient returns a client for server.  GitHub Enterprise
+// servers use the api and uploads urls of the user configuration, by default
+// https://{server}/api/v3/ and https://{server}/api/uploads/
+// githubUploadsURL returns the uploads url of the GitHub Enterprise API api:
+// https://host/api/uploads/ for https://host/api/v3/, api otherwise
+func githubUploadsURL(api string) string {
+	u, err := url.Parse(api)
+	if err != nil || !strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3") {
+		return api
 	}
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3") + "/api/uploads/"
+	return u.String()
+}
+
+func newGithubServerClient(appCtx *model.AppContext, server string) (*github.Client, error) {
+	httpClient := newGithubHttpClient(appCtx, server)
+	if server == "github.com" {
+		return github.NewClient(httpClient), nil
+	}
+
+	api, uploads := fmt.Sprintf("https://%s/", server), ""
+	if cfg := appCtx.UserConfig.GetServer(server); cfg != nil && cfg.API != "" {
+		api = cfg.API
+		uploads = cfg.Uploads
+	}
+	if uploads == "" {
+		uploads = githubUploadsURL(api)
+	}
+	client, err := github.NewEnterpriseClient(api, uploads, httpClient)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("github client for %s: %w", server, err)
 	}
-	return nil
+	return client, nil
+}
+
+// newGithubHttpClient returns an http client authenticated with the token of
+// server in the user configuration.  nil when there is no token
+func newGithubHttpClient(appCtx *model.AppContext, server string) *http.Client {
+	githubAccessToken := appCtx.UserConfig.GetServerToken(server)
+	if githubAccessToken == "" {
+		return nil
+	}
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: githubAccessToken},
+	)
+	return oauth2.NewClient(context.Background(), ts)
 }
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/google/go-github/v47/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGithubReleases serves the releases of owner/repo, the ones in
+// prereleases are marked as pre-release.  Only exact tags of releases can be
+// fetched, latest is the first release not marked as pre-release
+func fakeGithubReleases(t *testing.T, prereleases []string, names ...string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var releases []map[string]interface{}
+		var latest map[string]interface{}
+		for i, name := range names {
+			release := map[string]interface{}{"id": i + 1, "name": name, "tag_name": name}
+			for _, pre := range prereleases {
+				if pre == name {
+					release["prerelease"] = true
+				}
+			}
+			if latest == nil && release["prerelease"] == nil {
+				latest = release
+			}
+			releases = append(releases, release)
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo/releases":
+			json.NewEncoder(w).Encode(releases)
+		case "/repos/owner/repo/releases/latest":
+			json.NewEncoder(w).Encode(latest)
+		default:
+			for _, release := range releases {
+				if r.URL.Path == "/repos/owner/repo/releases/tags/"+release["tag_name"].(string) {
+					json.NewEncoder(w).Encode(release)
+					return
+				}
+			}
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	githubClientMap["github.com"] = client
+	t.Cleanup(func() { delete(githubClientMap, "github.com") })
+}
+
+func TestGithubResolverResolvePrerelease(t *testing.T) {
+	fakeGithubReleases(t, []string{"v3.2.0"}, "v3.2.0", "v3.1.0-rc1", "v3.0.0", "v2.9.0")
+
+	d := []struct {
+		version    string
+		prerelease bool
+		expected   string
+	}{
+		{"", false, "3.0.0"},
+		{"3", false, "3.0.0"},
+		{"^3", false, "3.0.0"},
+		{"3-rc", false, "3.2.0"}, // opts in to releases marked as pre-release too
+		{"1.0.0-rc1 || 3.1.0-rc1", false, "3.1.0-rc1"},
+		{"3.1.0-rc1", false, "3.1.0-rc1"},
+		{"", true, "3.2.0"},
+		{"3", true, "3.2.0"},
+		{"~3.1", true, "3.1.0-rc1"},
+	}
+	for _, test := range d {
+		fc, err := model.NewCoordFromStr("github.com/owner/repo@" + test.version)
+		assert.NoError(t, err)
+		fc.Prerelease = test.prerelease
+		lc, err := NewGithubResolver(&model.AppContext{}).ResolveCoord(fc)
+		assert.NoError(t, err, test.version)
+		if lc != nil {
+			assert.Equal(t, test.expected, lc.Version.Canonical(), "%s prerelease=%v", test.version, test.prerelease)
+		}
+	}
+
+	// outdated only lists pre-releases when allowed
+	fc, _ := model.NewCoordFromStr("github.com/owner/repo@3")
+	versions, _, err := NewGithubResolver(&model.AppContext{}).ListVersions(fc)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 3)
+	fc.Prerelease = true
+	versions, _, _ = NewGithubResolver(&model.AppContext{}).ListVersions(fc)
+	assert.Len(t, versions, 4)
+}
+
+func TestGithubEnterpriseResolver(t *testing.T) {
+	assets := conformanceAssets(t)
+
+	// hidden releases are drafts, the Link header has the next page
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		release := func(c conformanceRelease) map[string]interface{} {
+			var list []map[string]interface{}
+			for i, a := range assets {
+				list = append(list, map[string]interface{}{
+					"id": i + 1, "name": a.name, "size": len(a.content), "browser_download_url": server.URL + "/download/" + a.name,
+				})
+			}
+			return map[string]interface{}{"name": c.tag, "tag_name": c.tag, "draft": c.hidden, "prerelease": c.prerelease, "assets": list}
+		}
+		releases := "/api/v3/repos/owner/repo/releases"
+		tag := conformanceTag(strings.TrimPrefix(r.URL.Path, releases+"/tags/"))
+		switch {
+		case r.URL.Path == releases:
+			page, next := conformancePage(r)
+			if next != 0 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d&per_page=30>; rel="next"`, server.URL, releases, next))
+			}
+			var list []interface{}
+			for _, c := range page {
+				list = append(list, release(c))
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.URL.Path == releases+"/latest":
+			for _, c := range conformanceReleases {
+				if !c.hidden && !c.prerelease {
+					json.NewEncoder(w).Encode(release(c))
+					return
+				}
+			}
+		case strings.HasPrefix(r.URL.Path, releases+"/tags/") && tag != nil:
+			json.NewEncoder(w).Encode(release(*tag))
+		case strings.HasPrefix(r.URL.Path, releases+"/assets/"):
+			i, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, releases+"/assets/"))
+			if i < 1 || i > len(assets) {
+				http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(assets[i-1].content)
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { delete(githubClientMap, "github.ourcompany.com") })
+
+	appCtx := &model.AppContext{
+		UserCacheDirName: t.TempDir(),
+		UserConfig: model.UserConfig{Servers: []model.UserConfigServer{
+			{Name: "github.ourcompany.com", Type: "github", API: server.URL + "/api/v3", Token: "secret"},
+		}},
+	}
+	testReleaseResolver(t, NewGithubResolver(appCtx), appCtx, "github.ourcompany.com", true)
+
+	// publishing uses the same urls, uploads default to api/uploads
+	publisher, err := NewGithubPublisher(appCtx, "github.ourcompany.com", "")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/api/v3/", publisher.client.BaseURL.String())
+	assert.Equal(t, server.URL+"/api/uploads/", publisher.client.UploadURL.String())
+}
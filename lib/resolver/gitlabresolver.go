@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/bazurto/bz/lib/model"
+)
+
+// GitLabResolver resolves the dependencies of gitlab.com and of the servers
+// with type "gitlab" in the user configuration from the assets (links) of
+// GitLab releases
+type GitLabResolver struct {
+	appCtx *model.AppContext
+}
+
+func NewGitLabResolver(appCtx *model.AppContext) *GitLabResolver {
+	return &GitLabResolver{appCtx}
+}
+
+func (o *GitLabResolver) String() string {
+	return "GitLabResolver{}"
+}
+
+// gitlabRelease is a release returned by the GitLab releases API
+type gitlabRelease struct {
+	Name            string `json:"name"`
+	TagName         string `json:"tag_name"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+	Assets          struct {
+		Links []gitlabLink `json:"links"`
+	} `json:"assets"`
+}
+
+// gitlabLink is a release asset, usually a generic package registry file
+type gitlabLink struct {
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+// downloadURL returns the url the asset is downloaded from
+func (o *gitlabLink) downloadURL() string {
+	if o.DirectAssetURL != "" {
+		return o.DirectAssetURL
+	}
+	return o.URL
+}
+
+// version returns the version of the release, from the tag.  Releases are
+// named freely on GitLab
+func (o *gitlabRelease) version() model.Version {
+	if o.TagName != "" {
+		return model.NewVersion(o.TagName)
+	}
+	return model.NewVersion(o.Name)
+}
+
+func (o *GitLabResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord, error) {
+	Debug.Printf("Start GitLabResolver.ResolveCoord(%s)", c)
+
+	// if not gitlab, then bail out
+	if !IsGitLabServer(o.appCtx, c.Server) {
+		return nil, nil
+	}
+
+	if o.appCtx.Offline {
+		return nil, fmt.Errorf("GitLabResolver.ResolveCoord(%s): cannot resolve in offline mode", c)
+	}
+
+	release, err := resolveRelease(o.glForge(c.Server), c)
+	if err != nil {
+		return nil, fmt.Errorf("GitLabResolver.ResolveCoord(): %w", err)
+	}
+	return lockedRelease(c, release), nil
+}
+
+func (o *GitLabResolver) DownloadResolvedCoord(lc *model.LockedCoord) (string, error, bool) {
+	Debug.Printf("Start GitLabResolver.DownloadResolvedCoord(%v)", lc)
+
+	// if not gitlab, then bail out
+	if !IsGitLabServer(o.appCtx, lc.Server) {
+		return "", nil, false
+	}
+
+	dir, err := downloadRelease(o.appCtx, o.glForge(lc.Server), lc)
+	if err != nil {
+		return "", fmt.Errorf("GitLabResolver.DownloadResolvedCoord(): %w", err), false
+	}
+	return dir, nil, true
+}
+
+// ListVersions implements VersionLister
+func (o *GitLabResolver) ListVersions(c *model.FuzzyCoord) ([]model.Version, bool, error) {
+	if !IsGitLabServer(o.appCtx, c.Server) {
+		return nil, false, nil
+	}
+	if o.appCtx.Offline {
+		return nil, true, fmt.Errorf("GitLabResolver.ListVersions(%s): cannot list versions in offline mode", c)
+	}
+
+	versions, err := releaseVersions(o.glForge(c.Server), c)
+	if err != nil {
+		return nil, true, fmt.Errorf("GitLabResolver.ListVersions(): %w", err)
+	}
+	return versions, true, nil
+}
+
+// LockPlatforms implements PlatformLocker.  Digests are read from the
+// checksums asset of the release, other assets are not downloaded
+func (o *GitLabResolver) LockPlatforms(lc *model.LockedCoord, platforms []model.Platform) (bool, error) {
+	if !IsGitLabServer(o.appCtx, lc.Server) {
+		return false, nil
+	}
+
+	if err := lockReleasePlatforms(o.appCtx, o.glForge(lc.Server), lc, platforms); err != nil {
+		return true, fmt.Errorf("GitLabResolver.LockPlatforms(%s): %w", lc, err)
+	}
+	return true, nil
+}
+
+// gitlabForge is the releaseForge of a GitLab server.  GitLab does not flag
+// pre-releases, only their versions tell
+type gitlabForge struct {
+	client *forgeClient
+}
+
+// glForge returns the releaseForge of server
+func (o *GitLabResolver) glForge(server string) *gitlabForge {
+	return &gitlabForge{client: o.glClient(server)}
+}
+
+// forgeRelease converts a release of the API
+func (o *gitlabRelease) forgeRelease() *forgeRelease {
+	release := &forgeRelease{version: o.version()}
+	for _, link := range o.Assets.Links {
+		release.assets = append(release.assets, &forgeAsset{name: link.Name, url: link.downloadURL()})
+	}
+	return release
+}
+
+func (o *gitlabForge) getRelease(owner, repo, tag string) (*forgeRelease, error) {
+	var release gitlabRelease
+	found, err := o.client.get(fmt.Sprintf("%s/releases/%s", glProjectPath(owner, repo), url.PathEscape(tag)), &release)
+	if err != nil || !found {
+		return nil, err
+	}
+	return release.forgeRelease(), nil
+}
+
+func (o *gitlabForge) open(owner, repo string, asset *forgeAsset) (io.ReadCloser, error) {
+	return o.client.open(asset.url)
+}
+
+// listReleases returns all releases of a project except the upcoming ones
+func (o *gitlabForge) listReleases(owner, repo string) ([]*forgeRelease, error) {
+	perPage := 30
+	page := 1
+	var all []*forgeRelease
+
+	for page != 0 {
+		Debug.Printf(" | call listReleases %s/%s/%d/%d", owner, repo, page, perPage)
+		var releases []*gitlabRelease
+		resp, err := o.client.request(fmt.Sprintf("%s/releases?page=%d&per_page=%d", glProjectPath(owner, repo), page, perPage))
+		if err != nil {
+			return nil, fmt.Errorf("listReleases(): %w", err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&releases)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listReleases(): %w", err)
+		}
+		for _, release := range releases {
+			if release.UpcomingRelease {
+				continue
+			}
+			all = append(all, release.forgeRelease())
+		}
+
+		page, _ = strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	}
+	return all, nil
+}
+
+// glClient returns the client of the API of server, authenticated with the
+// private token of the user configuration
+func (o *GitLabResolver) glClient(server string) *forgeClient {
+	return &forgeClient{
+		server: server,
+		api:    GitLabAPI(o.appCtx, server),
+		header: "PRIVATE-TOKEN",
+		value:  o.appCtx.UserConfig.GetServerToken(server),
+	}
+}
+
+// glProjectPath returns the API path of the project owner/repo.  owner can be
+// a group with subgroups
+func glProjectPath(owner, repo string) string {
+	return "projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+// IsGitLabServer returns true if the dependencies of server are resolved
+// with the GitLab API: gitlab.com and servers with type "gitlab" in the user
+// configuration (self-hosted GitLab)
+func IsGitLabServer(appCtx *model.AppContext, server string) bool {
+	return server == "gitlab.com" || appCtx.UserConfig.GetServerType(server) == model.ServerTypeGitlab
+}
+
+// GitLabAPI returns the API url of server, https://{server}/api/v4 unless
+// configured in the user configuration
+func GitLabAPI(appCtx *model.AppContext, server string) string {
+	if cfg := appCtx.UserConfig.GetServer(server); cfg != nil && cfg.API != "" {
+		return cfg.API
+	}
+	return fmt.Sprintf("https://%s/api/v4", server)
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGitlabReleases serves the conformance releases of owner/repo, hidden
+// releases are upcoming ones.  The assets are generic package registry
+// links.  Every request must have the private token.  The package registry
+// redirects to a storage server that must not get it
+func fakeGitlabReleases(t *testing.T) *httptest.Server {
+	assets := conformanceAssets(t)
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "" {
+			http.Error(w, "token leaked", http.StatusBadRequest)
+			return
+		}
+		for _, a := range assets {
+			if strings.HasSuffix(r.URL.Path, "/"+a.name) {
+				w.Write(a.content)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(storage.Close)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			http.Error(w, `{"message": "401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		release := func(c conformanceRelease) map[string]interface{} {
+			var links []map[string]interface{}
+			for _, a := range assets {
+				links = append(links, map[string]interface{}{
+					"name": a.name, "url": server.URL + "/api/v4/projects/1/packages/generic/repo/" + c.tag + "/" + a.name,
+				})
+			}
+			return map[string]interface{}{
+				"name":             "Release " + c.tag,
+				"tag_name":         c.tag,
+				"upcoming_release": c.hidden,
+				"assets":           map[string]interface{}{"links": links},
+			}
+		}
+		releases := "/api/v4/projects/owner%2Frepo/releases"
+		tag := conformanceTag(strings.TrimPrefix(r.URL.EscapedPath(), releases+"/"))
+		switch {
+		case r.URL.EscapedPath() == releases:
+			page, next := conformancePage(r)
+			w.Header().Set("X-Next-Page", "")
+			if next != 0 {
+				w.Header().Set("X-Next-Page", strconv.Itoa(next))
+			}
+			var list []interface{}
+			for _, c := range page {
+				list = append(list, release(c))
+			}
+			json.NewEncoder(w).Encode(list)
+		case strings.HasPrefix(r.URL.EscapedPath(), releases+"/") && tag != nil:
+			json.NewEncoder(w).Encode(release(*tag))
+		case strings.HasPrefix(r.URL.Path, "/api/v4/projects/1/packages/generic/repo/"):
+			http.Redirect(w, r, storage.URL+"/bucket/"+strings.TrimPrefix(r.URL.Path, "/api/v4/projects/1/packages/generic/repo/"), http.StatusFound)
+		default:
+			http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitLabResolver(t *testing.T) {
+	server := fakeGitlabReleases(t)
+	appCtx := &model.AppContext{
+		UserCacheDirName: t.TempDir(),
+		UserConfig: model.UserConfig{Servers: []model.UserConfigServer{
+			{Name: "gitlab.ourcompany.com", Type: "gitlab", API: server.URL + "/api/v4", Token: "secret"},
+		}},
+	}
+	testReleaseResolver(t, NewGitLabResolver(appCtx), appCtx, "gitlab.ourcompany.com", false)
+
+	assert.True(t, IsGitLabServer(appCtx, "gitlab.com"))
+	assert.False(t, IsGitLabServer(appCtx, "github.com"))
+	assert.Equal(t, "https://gitlab.com/api/v4", GitLabAPI(appCtx, "gitlab.com"))
+	assert.Equal(t, "projects/group%2Fsub%2Fproject", glProjectPath("group/sub", "project"))
+}
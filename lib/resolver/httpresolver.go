@@ -0,0 +1,369 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+var (
+	// httpVersionRegexp matches the versions in an index: 1.2.3, v1.2.3-rc1
+	httpVersionRegexp = regexp.MustCompile(`^v?\d+(\.\d+)*(-[0-9A-Za-z.]+)?(\+[0-9A-Za-z.]+)?$`)
+	// httpHrefRegexp matches the links of an html directory listing
+	httpHrefRegexp = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+)
+
+// Index formats of http servers
+const (
+	IndexFormatJSON = "json" // ["1.2.3", ...], [{"version": "1.2.3"}, ...] or {"versions": ...}
+	IndexFormatHTML = "html" // directory listing with a link per version
+)
+
+// HTTPResolver resolves the dependencies of the servers with type "http" in
+// the user configuration.  Versions are read from the index of the server and
+// assets downloaded from its download url template, e.g.:
+//
+//	server "nodejs.org" {
+//		type     = "http"
+//		index    = "https://nodejs.org/dist/index.json"
+//		download = "https://nodejs.org/dist/v{version}/node-v{version}-{os}-{arch}.{ext}"
+//		binDir   = "node-v{version}-{os}-{arch}/bin"
+//		arch     = { amd64 = "x64" }
+//	}
+type HTTPResolver struct {
+	appCtx *model.AppContext
+}
+
+func NewHTTPResolver(appCtx *model.AppContext) *HTTPResolver {
+	return &HTTPResolver{appCtx}
+}
+
+func (o *HTTPResolver) String() string {
+	return "HTTPResolver{}"
+}
+
+func (o *HTTPResolver) ResolveCoord(c *model.FuzzyCoord) (*model.LockedCoord, error) {
+	Debug.Printf("Start HTTPResolver.ResolveCoord(%s)", c)
+
+	// if not http, then bail out
+	if !IsHTTPServer(o.appCtx, c.Server) {
+		return nil, nil
+	}
+
+	if o.appCtx.Offline {
+		return nil, fmt.Errorf("HTTPResolver.ResolveCoord(%s): cannot resolve in offline mode", c)
+	}
+
+	versions, err := o.httpListVersions(c.Server, c.Owner, c.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("HTTPResolver.ResolveCoord(): %w", err)
+	}
+	i := bestRelease(versions, nil, c.Constraint())
+	if i < 0 {
+		return nil, fmt.Errorf("HTTPResolver.ResolveCoord(): dependency %s/%s@%s not found", c.Owner, c.Repo, c.Constraint())
+	}
+
+	lc := &model.LockedCoord{
+		Server:  c.Server,
+		Owner:   c.Owner,
+		Repo:    c.Repo,
+		Version: versions[i],
+	}
+
+	// the digest is only known once the asset is downloaded
+	if asset, err := o.httpFindAsset(lc, model.CurrentPlatform()); err == nil {
+		lc.Asset = asset
+	} else {
+		Debug.Printf("HTTPResolver.ResolveCoord(%s): %s", c, err)
+	}
+	return lc, nil
+}
+
+func (o *HTTPResolver) DownloadResolvedCoord(lc *model.LockedCoord) (string, error, bool) {
+	Debug.Printf("Start HTTPResolver.DownloadResolvedCoord(%v)", lc)
+
+	// if not http, then bail out
+	if !IsHTTPServer(o.appCtx, lc.Server) {
+		return "", nil, false
+	}
+
+	dir := cacheVersionDir(o.appCtx, lc)
+	extractToDir := filepath.Join(dir, "extracted")
+
+	// nothing to do... already installed
+	if utils.FileExists(extractToDir) {
+		if err := verifyCachedAsset(o.appCtx, lc, dir); err != nil {
+			return "", fmt.Errorf("HTTPResolver.DownloadResolvedCoord(): %w", err), false
+		}
+		return extractToDir, nil, true
+	}
+
+	if o.appCtx.Offline {
+		return "", fmt.Errorf("HTTPResolver.DownloadResolvedCoord(%s): not in cache and running in offline mode", lc), false
+	}
+
+	asset, err := o.httpFindAsset(lc, model.CurrentPlatform())
+	if err != nil {
+		return "", fmt.Errorf("HTTPResolver.DownloadResolvedCoord(): %w", err), false
+	}
+
+	downloaded := &model.LockedAsset{Name: asset.Name, URL: asset.URL}
+	err = downloadAsset(o.appCtx, lc, dir, downloaded, func() (io.ReadCloser, error) {
+		return o.httpClient(lc.Server).open(downloaded.URL)
+	})
+	if err != nil {
+		return "", fmt.Errorf("HTTPResolver.DownloadResolvedCoord(): %w", err), false
+	}
+
+	// plain tools are not bz packages
+	if err := o.writeLockIfMissing(lc, extractToDir); err != nil {
+		return "", fmt.Errorf("HTTPResolver.DownloadResolvedCoord(): %w", err), false
+	}
+	return extractToDir, nil, true
+}
+
+// ListVersions implements VersionLister
+func (o *HTTPResolver) ListVersions(c *model.FuzzyCoord) ([]model.Version, bool, error) {
+	if !IsHTTPServer(o.appCtx, c.Server) {
+		return nil, false, nil
+	}
+	if o.appCtx.Offline {
+		return nil, true, fmt.Errorf("HTTPResolver.ListVersions(%s): cannot list versions in offline mode", c)
+	}
+
+	versions, err := o.httpListVersions(c.Server, c.Owner, c.Repo)
+	if err != nil {
+		return nil, true, fmt.Errorf("HTTPResolver.ListVersions(): %w", err)
+	}
+	return versions, true, nil
+}
+
+// LockPlatforms implements PlatformLocker.  The servers have no checksums,
+// digests are only recorded for the assets that are downloaded
+func (o *HTTPResolver) LockPlatforms(lc *model.LockedCoord, platforms []model.Platform) (bool, error) {
+	if !IsHTTPServer(o.appCtx, lc.Server) {
+		return false, nil
+	}
+
+	locked := make(map[string]*model.LockedAsset)
+	for _, p := range platforms {
+		if a, ok := lc.Platforms[p.String()]; ok {
+			locked[p.String()] = a
+			continue
+		}
+		if o.appCtx.Offline {
+			return true, fmt.Errorf("HTTPResolver.LockPlatforms(%s): cannot lock platforms in offline mode", lc)
+		}
+		asset, err := o.httpFindAsset(lc, p)
+		if err != nil {
+			return true, fmt.Errorf("HTTPResolver.LockPlatforms(%s): %s: %w", lc, p, err)
+		}
+		if lc.Asset != nil && lc.Asset.Name == asset.Name {
+			asset.Sha256 = lc.Asset.Sha256 // downloaded for this platform
+		}
+		locked[p.String()] = asset
+	}
+	lc.Platforms = locked
+	return true, nil
+}
+
+// httpListVersions returns the versions in the index of owner/repo
+func (o *HTTPResolver) httpListVersions(server, owner, repo string) ([]model.Version, error) {
+	cfg := o.appCtx.UserConfig.GetServer(server)
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("httpListVersions(): server %s has no index", server)
+	}
+
+	u := expandURLTemplate(cfg, cfg.Index, &model.LockedCoord{Server: server, Owner: owner, Repo: repo}, model.CurrentPlatform(), "")
+	Debug.Printf(" | call httpListVersions %s", u)
+	r, err := o.httpClient(server).open(u)
+	if err != nil {
+		return nil, fmt.Errorf("httpListVersions(): %w", err)
+	}
+	defer r.Close()
+
+	var names []string
+	switch strings.ToLower(cfg.IndexFormat) {
+	case "", IndexFormatJSON:
+		names, err = parseJSONIndex(r)
+	case IndexFormatHTML:
+		names, err = parseHTMLIndex(r)
+	default:
+		return nil, fmt.Errorf("httpListVersions(): unknown index format `%s` for server %s", cfg.IndexFormat, server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("httpListVersions(%s): %w", u, err)
+	}
+
+	var versions []model.Version
+	for _, name := range names {
+		if httpVersionRegexp.MatchString(name) {
+			versions = append(versions, model.NewVersion(name))
+		}
+	}
+	return versions, nil
+}
+
+// httpFindAsset returns the asset of lc for platform.  When the download
+// template has {ext}, the assetExtensions are tried in order
+func (o *HTTPResolver) httpFindAsset(lc *model.LockedCoord, platform model.Platform) (*model.LockedAsset, error) {
+	cfg := o.appCtx.UserConfig.GetServer(lc.Server)
+	if cfg.Download == "" {
+		return nil, fmt.Errorf("server %s has no download url template", lc.Server)
+	}
+
+	var candidates []string
+	if strings.Contains(cfg.Download, "{ext}") {
+		for _, ext := range assetExtensions {
+			candidates = append(candidates, expandURLTemplate(cfg, cfg.Download, lc, platform, ext))
+		}
+	} else {
+		candidates = append(candidates, expandURLTemplate(cfg, cfg.Download, lc, platform, ""))
+	}
+	if len(candidates) == 1 {
+		return newHTTPAsset(candidates[0])
+	}
+
+	client := o.httpClient(lc.Server)
+	for _, u := range candidates {
+		found, err := client.exists(u)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return newHTTPAsset(u)
+		}
+	}
+	return nil, fmt.Errorf("could not find asset %s in depedency [%s]", strings.Join(candidates, ","), lc)
+}
+
+// writeLockIfMissing writes the lock file of a dependency that has none with
+// the bin dir of the server configuration, the root of the asset by default
+func (o *HTTPResolver) writeLockIfMissing(lc *model.LockedCoord, extractToDir string) error {
+	lockFile := filepath.Join(extractToDir, o.appCtx.LockFileName)
+	if utils.FileExists(lockFile) {
+		return nil
+	}
+
+	lcc := model.LockedConfigContent{BinDir: "$DIR"}
+	if cfg := o.appCtx.UserConfig.GetServer(lc.Server); cfg.BinDir != "" {
+		lcc.BinDir = path.Join("$DIR", expandURLTemplate(cfg, cfg.BinDir, lc, model.CurrentPlatform(), ""))
+	}
+	b, err := json.MarshalIndent(&lcc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(lockFile, b, 0644)
+}
+
+// httpClient returns the client of server, authenticated with the token of
+// the user configuration
+func (o *HTTPResolver) httpClient(server string) *forgeClient {
+	client := &forgeClient{
+		server: server,
+		api:    o.appCtx.UserConfig.GetServer(server).Index,
+		header: "Authorization",
+	}
+	if token := o.appCtx.UserConfig.GetServerToken(server); token != "" {
+		client.value = "Bearer " + token
+	}
+	return client
+}
+
+func newHTTPAsset(u string) (*model.LockedAsset, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LockedAsset{Name: path.Base(parsed.Path), URL: u}, nil
+}
+
+// expandURLTemplate replaces {owner}, {repo}, {version}, {os}, {arch} and
+// {ext} in tpl.  {os} and {arch} are mapped to the names used by the server
+func expandURLTemplate(cfg *model.UserConfigServer, tpl string, lc *model.LockedCoord, platform model.Platform, ext string) string {
+	goos, goarch := platform.OS, platform.Arch
+	if v, ok := cfg.OS[goos]; ok {
+		goos = v
+	}
+	if v, ok := cfg.Arch[goarch]; ok {
+		goarch = v
+	}
+	return strings.NewReplacer(
+		"{owner}", lc.Owner,
+		"{repo}", lc.Repo,
+		"{version}", lc.Version.Canonical(),
+		"{os}", goos,
+		"{arch}", goarch,
+		"{ext}", ext,
+	).Replace(tpl)
+}
+
+// parseJSONIndex returns the versions of a json index: a list of versions,
+// a list of objects with a version or an object with the versions
+func parseJSONIndex(r io.Reader) ([]string, error) {
+	var index interface{}
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return nil, err
+	}
+	if m, ok := index.(map[string]interface{}); ok {
+		index = m["versions"]
+	}
+
+	var names []string
+	switch v := index.(type) {
+	case []interface{}:
+		for _, e := range v {
+			switch e := e.(type) {
+			case string:
+				names = append(names, e)
+			case map[string]interface{}:
+				if s, ok := e["version"].(string); ok {
+					names = append(names, s)
+				}
+			}
+		}
+	case map[string]interface{}: // version => details
+		for k := range v {
+			names = append(names, k)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected json index, expected a list of versions")
+	}
+	return names, nil
+}
+
+// parseHTMLIndex returns the last path element of every link of a directory
+// listing: <a href="v1.2.3/">, <a href="/tool/1.2.3/">
+func parseHTMLIndex(r io.Reader) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, m := range httpHrefRegexp.FindAllStringSubmatch(string(b), -1) {
+		href := m[1]
+		if u, err := url.Parse(href); err == nil {
+			href = u.Path
+		}
+		names = append(names, path.Base(href))
+	}
+	return names, nil
+}
+
+// IsHTTPServer returns true if the dependencies of server are plain downloads:
+// servers with type "http" in the user configuration
+func IsHTTPServer(appCtx *model.AppContext, server string) bool {
+	return appCtx.UserConfig.GetServerType(server) == model.ServerTypeHTTP
+}
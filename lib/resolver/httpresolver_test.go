@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHTTPServer serves a json index, an html directory listing, tgz assets
+// of tool and a raw binary.  HEAD requests are not allowed for the assets of
+// tool.  The bucket refuses HEAD requests and answers 403 for missing files,
+// like presigned urls
+func fakeHTTPServer(t *testing.T, content []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/tool/v") {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/bucket/") {
+			if r.Method == http.MethodHead || r.URL.Path != "/bucket/tool-1.5.0.tgz" {
+				http.Error(w, "access denied", http.StatusForbidden)
+			} else if r.Header.Get("Range") == "bytes=0-0" {
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(content[:1])
+			} else {
+				w.Write(content)
+			}
+			return
+		}
+		switch r.URL.Path {
+		case "/tool/index.json":
+			w.Write([]byte(`[{"version": "v2.0.0-rc1"}, {"version": "v1.5.0"}, {"version": "v1.4.2"}]`))
+		case "/tool/":
+			w.Write([]byte(`<html><body>
+<a href="../">../</a>
+<a href="/tool/1.4.2/">tool_1.4.2</a>
+<a href='/tool/1.5.0/'>tool_1.5.0</a>
+<a href="index.json">index.json</a>
+</body></html>`))
+		case "/tool/v1.5.0/tool-1.5.0-" + runtime.GOOS + "-cpu.tgz":
+			w.Write(content)
+		case "/bin/1.5.0/" + runtime.GOOS + "/" + runtime.GOARCH + "/tool":
+			w.Write([]byte("#!/bin/sh\necho tool\n"))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPResolver(t *testing.T) {
+	content := testTgz(t)
+	server := fakeHTTPServer(t, content)
+	appCtx := &model.AppContext{
+		UserCacheDirName: t.TempDir(),
+		LockFileName:     ".bz.lock",
+		UserConfig: model.UserConfig{Servers: []model.UserConfigServer{
+			{
+				Name:     "tools.example.com",
+				Type:     "http",
+				Index:    server.URL + "/{repo}/index.json",
+				Download: server.URL + "/{repo}/v{version}/{repo}-{version}-{os}-{arch}.{ext}",
+				Arch:     map[string]string{runtime.GOARCH: "cpu"},
+			},
+			{
+				Name:     "bucket.example.com",
+				Type:     "http",
+				Index:    server.URL + "/{repo}/index.json",
+				Download: server.URL + "/bucket/{repo}-{version}.{ext}",
+			},
+			{
+				Name:        "raw.example.com",
+				Type:        "http",
+				Index:       server.URL + "/{repo}/",
+				IndexFormat: "html",
+				Download:    server.URL + "/bin/{version}/{os}/{arch}/{repo}",
+			},
+		}},
+	}
+	r := NewHTTPResolver(appCtx)
+
+	d := map[string]string{
+		"tools.example.com/acme/tool@":     "1.5.0",
+		"tools.example.com/acme/tool@1.4":  "1.4.2",
+		"tools.example.com/acme/tool@2-rc": "2.0.0-rc1",
+		"raw.example.com/acme/tool@^1":     "1.5.0",
+		"raw.example.com/acme/tool@~1.4":   "1.4.2",
+	}
+	for coord, expected := range d {
+		fc, err := model.NewCoordFromStr(coord)
+		assert.NoError(t, err)
+		lc, err := r.ResolveCoord(fc)
+		assert.NoError(t, err, coord)
+		if assert.NotNil(t, lc, coord) {
+			assert.Equal(t, expected, lc.Version.Canonical(), coord)
+		}
+	}
+
+	// {ext} is found by trying the asset extensions, the package has a lock file
+	fc, _ := model.NewCoordFromStr("tools.example.com/acme/tool@1.5")
+	lc, err := r.ResolveCoord(fc)
+	assert.NoError(t, err)
+	if assert.NotNil(t, lc.Asset) {
+		assert.Equal(t, "tool-1.5.0-"+runtime.GOOS+"-cpu.tgz", lc.Asset.Name)
+	}
+	dir, err, handled := r.DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	b, err := os.ReadFile(filepath.Join(dir, ".bz.lock"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(b))
+	assert.NotEmpty(t, lc.Asset.Sha256)
+
+	// without HEAD requests the first byte is requested
+	fc, _ = model.NewCoordFromStr("bucket.example.com/acme/tool@1.5")
+	lc, err = r.ResolveCoord(fc)
+	assert.NoError(t, err)
+	if assert.NotNil(t, lc.Asset) {
+		assert.Equal(t, "tool-1.5.0.tgz", lc.Asset.Name)
+	}
+	_, err, _ = r.DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+
+	// raw binaries are executables in a package with a generated lock file
+	fc, _ = model.NewCoordFromStr("raw.example.com/acme/tool@1.5")
+	lc, err = r.ResolveCoord(fc)
+	assert.NoError(t, err)
+	dir, err, _ = r.DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+	if info, err := os.Stat(filepath.Join(dir, "tool")); assert.NoError(t, err) && runtime.GOOS != "windows" {
+		assert.NotZero(t, info.Mode()&0100)
+	}
+	lcc, err := model.LockedConfigContentFromFile(filepath.Join(dir, ".bz.lock"))
+	assert.NoError(t, err)
+	assert.Equal(t, "$DIR", lcc.BinDir)
+
+	// assets of other platforms are locked without downloading them
+	handled, err = r.LockPlatforms(lc, []model.Platform{{OS: "plan9", Arch: "arm"}})
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, server.URL+"/bin/1.5.0/plan9/arm/tool", lc.Platforms["plan9-arm"].URL)
+
+	// other servers are left to other resolvers
+	fc, _ = model.NewCoordFromStr("github.com/owner/repo@1")
+	lc, err = r.ResolveCoord(fc)
+	assert.NoError(t, err)
+	assert.Nil(t, lc)
+}
+
+func TestParseIndex(t *testing.T) {
+	d := map[string][]string{
+		`["1.0.0", "1.1.0"]`: {"1.0.0", "1.1.0"},
+		`[{"version": "v1.0.0", "lts": false}, {"files": []}]`:     {"v1.0.0"},
+		`{"name": "tool", "versions": {"1.0.0": {}, "1.1.0": {}}}`: {"1.0.0", "1.1.0"},
+		`{"versions": ["1.0.0"]}`:                                  {"1.0.0"},
+	}
+	for index, expected := range d {
+		names, err := parseJSONIndex(strings.NewReader(index))
+		assert.NoError(t, err, index)
+		sort.Strings(names)
+		assert.Equal(t, expected, names, index)
+	}
+	_, err := parseJSONIndex(strings.NewReader(`"1.0.0"`))
+	assert.Error(t, err)
+
+	names, err := parseHTMLIndex(strings.NewReader(`<a href="v1.0.0/">v1.0.0/</a><A HREF="https://example.com/dist/v1.1.0/?x=1">`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, names)
+}
@@ -0,0 +1,312 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// Rules shared by the resolvers of forges that publish dependencies as
+// release assets: GitHub, GitLab and Gitea
+
+// releaseForge is the API of a forge server that publishes dependencies as
+// release assets
+type releaseForge interface {
+	// listReleases returns every published release of owner/repo
+	listReleases(owner, repo string) ([]*forgeRelease, error)
+	// getRelease returns the release of tag, nil if there is none
+	getRelease(owner, repo, tag string) (*forgeRelease, error)
+	// open downloads asset
+	open(owner, repo string, asset *forgeAsset) (io.ReadCloser, error)
+}
+
+// forgeRelease is a release of a forge
+type forgeRelease struct {
+	version    model.Version
+	prerelease bool // flagged as pre-release by the forge
+	assets     []*forgeAsset
+}
+
+// forgeAsset is an asset of a release
+type forgeAsset struct {
+	id   int64 // used by the API to download it, GitHub only
+	name string
+	url  string
+	size int64
+}
+
+// asset returns the asset of c for platform, the first one found in the
+// priority order of possibleAssetNames
+func (o *forgeRelease) asset(c *model.LockedCoord, platform model.Platform) (*forgeAsset, error) {
+	var names []string
+	for _, a := range o.assets {
+		names = append(names, a.name)
+	}
+	i, err := assetIndex(c, platform, names)
+	if err != nil {
+		return nil, err
+	}
+	return o.assets[i], nil
+}
+
+// resolveRelease returns the release of c, the highest one satisfying its
+// constraint.  Exact versions are looked up by their v{version} tag first
+func resolveRelease(f releaseForge, c *model.FuzzyCoord) (*forgeRelease, error) {
+	constraint := c.Constraint()
+	if exact, ok := constraint.Exact(); ok {
+		Debug.Printf(" | get release %s/%s v%s", c.Owner, c.Repo, exact)
+		release, err := f.getRelease(c.Owner, c.Repo, fmt.Sprintf("v%s", exact))
+		if err != nil || release != nil {
+			return release, err
+		}
+	}
+	return findRelease(f, c.Owner, c.Repo, constraint)
+}
+
+// findRelease returns the highest release that satisfies constraint
+func findRelease(f releaseForge, owner, repo string, constraint model.VersionConstraint) (*forgeRelease, error) {
+	Debug.Printf("findRelease(%s, %s, %s)", owner, repo, constraint)
+	releases, err := f.listReleases(owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("findRelease(): %w", err)
+	}
+
+	var versions []model.Version
+	var flagged []bool
+	for _, release := range releases {
+		versions = append(versions, release.version)
+		flagged = append(flagged, release.prerelease)
+	}
+	if i := bestRelease(versions, flagged, constraint); i >= 0 {
+		Debug.Println(" | returning ", releases[i].version.Original())
+		return releases[i], nil
+	}
+	return nil, fmt.Errorf("dependency %s/%s@%s not found", owner, repo, constraint)
+}
+
+// lockedRelease returns the locked coord of c at release with the asset of
+// the current platform.  The digest is only known once the asset is
+// downloaded
+func lockedRelease(c *model.FuzzyCoord, release *forgeRelease) *model.LockedCoord {
+	lc := &model.LockedCoord{
+		Server:  c.Server,
+		Owner:   c.Owner,
+		Repo:    c.Repo,
+		Version: release.version,
+	}
+	if asset, err := release.asset(lc, model.CurrentPlatform()); err == nil {
+		lc.Asset = &model.LockedAsset{Name: asset.name, URL: asset.url, Size: asset.size}
+	} else {
+		Debug.Printf("lockedRelease(%s): %s", c, err)
+	}
+	return lc
+}
+
+// getLockedRelease returns the release of the locked version of lc.  The tag
+// is v{version} by convention, other tags are looked up in the list of
+// releases
+func getLockedRelease(f releaseForge, lc *model.LockedCoord) (*forgeRelease, error) {
+	release, err := f.getRelease(lc.Owner, lc.Repo, fmt.Sprintf("v%s", lc.Version.Canonical()))
+	if err != nil || release != nil {
+		return release, err
+	}
+	constraint, err := model.NewVersionConstraint("=" + lc.Version.Canonical())
+	if err != nil {
+		return nil, err
+	}
+	return findRelease(f, lc.Owner, lc.Repo, constraint.WithPrerelease())
+}
+
+// releaseVersions returns the versions of the releases of c.  Releases
+// flagged as pre-release are skipped unless the constraint of c allows
+// pre-releases
+func releaseVersions(f releaseForge, c *model.FuzzyCoord) ([]model.Version, error) {
+	releases, err := f.listReleases(c.Owner, c.Repo)
+	if err != nil {
+		return nil, err
+	}
+	prerelease := c.Constraint().Prerelease()
+	var versions []model.Version
+	for _, release := range releases {
+		if release.prerelease && !prerelease {
+			continue
+		}
+		versions = append(versions, release.version)
+	}
+	return versions, nil
+}
+
+// downloadRelease downloads and extracts the asset of the current platform
+// of lc to the cache, and returns the extracted dir.  Assets already in the
+// cache are verified against the lock
+func downloadRelease(appCtx *model.AppContext, f releaseForge, lc *model.LockedCoord) (string, error) {
+	dir := cacheVersionDir(appCtx, lc)
+	extractToDir := filepath.Join(dir, "extracted")
+
+	// nothing to do... already installed
+	if utils.FileExists(extractToDir) {
+		if err := verifyCachedAsset(appCtx, lc, dir); err != nil {
+			return "", err
+		}
+		return extractToDir, nil
+	}
+
+	if appCtx.Offline {
+		return "", fmt.Errorf("%s: not in cache and running in offline mode", lc)
+	}
+
+	release, err := getLockedRelease(f, lc)
+	if err != nil {
+		return "", err
+	}
+	asset, err := release.asset(lc, model.CurrentPlatform())
+	if err != nil {
+		return "", err
+	}
+
+	downloaded := &model.LockedAsset{Name: asset.name, URL: asset.url}
+	err = downloadAsset(appCtx, lc, dir, downloaded, func() (io.ReadCloser, error) {
+		return f.open(lc.Owner, lc.Repo, asset)
+	})
+	if err != nil {
+		return "", err
+	}
+	return extractToDir, nil
+}
+
+// lockReleasePlatforms sets lc.Platforms to the asset of every platform.
+// Digests are read from the checksums asset of the release, other assets are
+// not downloaded
+func lockReleasePlatforms(appCtx *model.AppContext, f releaseForge, lc *model.LockedCoord, platforms []model.Platform) error {
+	return lockPlatformAssets(lc, platforms, func() ([]*model.LockedAsset, map[string]string, error) {
+		if appCtx.Offline {
+			return nil, nil, fmt.Errorf("cannot lock platforms in offline mode")
+		}
+		release, err := getLockedRelease(f, lc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var assets []*model.LockedAsset
+		for _, a := range release.assets {
+			assets = append(assets, &model.LockedAsset{Name: a.name, URL: a.url, Size: a.size})
+		}
+		checksums, err := releaseChecksums(assets, func(i int) (io.ReadCloser, error) {
+			return f.open(lc.Owner, lc.Repo, release.assets[i])
+		})
+		return assets, checksums, err
+	})
+}
+
+// bestRelease returns the index of the highest release version satisfying
+// constraint, -1 if none.  Releases flagged as pre-release by the forge are
+// skipped unless the constraint allows pre-releases; flagged can be nil
+func bestRelease(versions []model.Version, flagged []bool, constraint model.VersionConstraint) int {
+	var candidates []model.Version
+	var indexes []int
+	for i, v := range versions {
+		if i < len(flagged) && flagged[i] && !constraint.Prerelease() {
+			continue
+		}
+		candidates = append(candidates, v)
+		indexes = append(indexes, i)
+	}
+	if i := constraint.Best(candidates); i >= 0 {
+		return indexes[i]
+	}
+	return -1
+}
+
+// assetIndex returns the index in names of the asset of c for platform, the
+// first one found in the priority order of possibleAssetNames
+func assetIndex(c *model.LockedCoord, platform model.Platform, names []string) (int, error) {
+	expectedNames := possibleAssetNames(c, platform)
+	for _, expected := range expectedNames {
+		for i, name := range names {
+			if expected.NameWithExt() == name {
+				Debug.Printf("found asset : %s", name)
+				return i, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf(
+		"could not find asset %s in depedency [%s]",
+		strings.Join(BzAssetArrHelper(expectedNames).CollectNames(), ","),
+		c.String(),
+	)
+}
+
+// releaseChecksums reads the checksums asset in assets with open and returns
+// asset name => hex digest.  Empty when there is no checksums asset
+func releaseChecksums(assets []*model.LockedAsset, open func(i int) (io.ReadCloser, error)) (map[string]string, error) {
+	for i, a := range assets {
+		if !isChecksumsAsset(a.Name) {
+			continue
+		}
+		readCloser, err := open(i)
+		if err != nil {
+			return nil, err
+		}
+		defer readCloser.Close()
+		return ParseChecksums(readCloser)
+	}
+	return map[string]string{}, nil
+}
+
+// lockPlatformAssets sets lc.Platforms to the asset of every platform.
+// Platforms already locked are kept, release is only called when a platform
+// is missing and returns the assets of the release of lc with the digests
+// of its checksums asset
+func lockPlatformAssets(lc *model.LockedCoord, platforms []model.Platform, release func() ([]*model.LockedAsset, map[string]string, error)) error {
+	locked := make(map[string]*model.LockedAsset)
+	var missing []model.Platform
+	for _, p := range platforms {
+		if a, ok := lc.Platforms[p.String()]; ok {
+			locked[p.String()] = a
+		} else {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		lc.Platforms = locked
+		return nil
+	}
+
+	assets, checksums, err := release()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, a := range assets {
+		names = append(names, a.Name)
+	}
+
+	for _, p := range missing {
+		i, err := assetIndex(lc, p, names)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		la := &model.LockedAsset{
+			Name:   assets[i].Name,
+			URL:    assets[i].URL,
+			Size:   assets[i].Size,
+			Sha256: checksums[assets[i].Name],
+		}
+		if la.Sha256 == "" && lc.Asset != nil && lc.Asset.Name == la.Name {
+			la.Sha256 = lc.Asset.Sha256 // downloaded for this platform
+		}
+		if la.Sha256 == "" {
+			Warn.Printf("%s: no digest for %s, the release has no %s", lc, la.Name, ChecksumsFileName)
+		}
+		locked[p.String()] = la
+	}
+	lc.Platforms = locked
+	return nil
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package resolver
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// conformanceRelease is a release of owner/repo served by the fake server of
+// a forge
+type conformanceRelease struct {
+	tag        string
+	prerelease bool // flagged as pre-release, by the forges that can
+	hidden     bool // draft or upcoming release, never resolved
+}
+
+// conformanceReleases are the releases of owner/repo, newest first.  The
+// tag of 1.9.3 is not v1.9.3
+var conformanceReleases = []conformanceRelease{
+	{tag: "v3.0.0", hidden: true},
+	{tag: "v2.1.0", prerelease: true},
+	{tag: "v2.0.0"},
+	{tag: "v1.10.0"},
+	{tag: "1.9.3"},
+	{tag: "v1.2.0-rc1"},
+}
+
+// conformanceAsset is an asset of every conformance release
+type conformanceAsset struct {
+	name    string
+	content []byte
+}
+
+// conformanceAssets returns the assets of every conformance release: the
+// package for every platform and its checksums
+func conformanceAssets(t *testing.T) []conformanceAsset {
+	content := testTgz(t)
+	return []conformanceAsset{
+		{name: "repo.tgz", content: content},
+		{name: ChecksumsFileName, content: []byte(fmt.Sprintf("%x  repo.tgz\n", sha256.Sum256(content)))},
+	}
+}
+
+// conformancePage returns the conformance releases of the page requested by
+// r, three per page, and the next page, 0 after the last one
+func conformancePage(r *http.Request) ([]conformanceRelease, int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	start, end := (page-1)*3, page*3
+	if start >= len(conformanceReleases) {
+		return nil, 0
+	}
+	if end >= len(conformanceReleases) {
+		return conformanceReleases[start:], 0
+	}
+	return conformanceReleases[start:end], page + 1
+}
+
+// conformanceTag returns the release of tag, nil for hidden releases
+func conformanceTag(tag string) *conformanceRelease {
+	for i := range conformanceReleases {
+		if conformanceReleases[i].tag == tag && !conformanceReleases[i].hidden {
+			return &conformanceReleases[i]
+		}
+	}
+	return nil
+}
+
+// testReleaseResolver checks the rules shared by the resolvers of forges.  r
+// resolves server/owner/repo from a fake server serving conformanceReleases
+// with conformanceAssets.  flags is false for forges that do not flag
+// pre-releases
+func testReleaseResolver(t *testing.T, r Resolver, appCtx *model.AppContext, server string, flags bool) {
+	coord := func(version string) *model.FuzzyCoord {
+		fc, err := model.NewCoordFromStr(server + "/owner/repo@" + version)
+		assert.NoError(t, err)
+		return fc
+	}
+
+	d := []struct {
+		version    string
+		prerelease bool
+		expected   string
+		unflagged  string // expected when the forge does not flag pre-releases
+	}{
+		{"", false, "2.0.0", "2.1.0"}, // hidden releases are ignored
+		{"2", false, "2.0.0", "2.1.0"},
+		{"2", true, "2.1.0", ""},
+		{"0.x || 2.x", false, "2.0.0", "2.1.0"},
+		{"1", false, "1.10.0", ""},
+		{"1.10.0", false, "1.10.0", ""},
+		{"1.9", false, "1.9.3", ""},
+		{"=1.9.3", false, "1.9.3", ""}, // not tagged v1.9.3: found in the list of releases
+		{">=1.2 <1.10", false, "1.9.3", ""},
+		{"1.2-rc", false, "1.2.0-rc1", ""},
+	}
+	for _, test := range d {
+		expected := test.expected
+		if !flags && test.unflagged != "" {
+			expected = test.unflagged
+		}
+		fc := coord(test.version)
+		fc.Prerelease = test.prerelease
+		lc, err := r.ResolveCoord(fc)
+		assert.NoError(t, err, test.version)
+		if assert.NotNil(t, lc, test.version) {
+			assert.Equal(t, expected, lc.Version.Canonical(), "%s prerelease=%v", test.version, test.prerelease)
+			assert.Equal(t, "repo.tgz", lc.Asset.Name, test.version)
+		}
+	}
+	_, err := r.ResolveCoord(coord("^4"))
+	assert.ErrorContains(t, err, "not found")
+
+	// downloaded and verified
+	lc, err := r.ResolveCoord(coord("1.9"))
+	assert.NoError(t, err)
+	dir, err, handled := r.DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.FileExists(t, filepath.Join(dir, ".bz.lock"))
+	assert.NotEmpty(t, lc.Asset.Sha256)
+
+	// pre-releases are only listed when allowed
+	fc := coord("1")
+	versions, handled, err := r.(VersionLister).ListVersions(fc)
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	if flags {
+		assert.Len(t, versions, 4)
+	} else {
+		assert.Len(t, versions, 5)
+	}
+	fc.Prerelease = true
+	versions, _, err = r.(VersionLister).ListVersions(fc)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 5)
+
+	// digests of other platforms come from the checksums asset
+	locked := &model.LockedCoord{Server: lc.Server, Owner: lc.Owner, Repo: lc.Repo, Version: lc.Version}
+	handled, err = r.(PlatformLocker).LockPlatforms(locked, []model.Platform{{OS: "windows", Arch: "arm64"}})
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	if assert.Contains(t, locked.Platforms, "windows-arm64") {
+		assert.Equal(t, "repo.tgz", locked.Platforms["windows-arm64"].Name)
+		assert.Equal(t, lc.Asset.Sha256, locked.Platforms["windows-arm64"].Sha256)
+	}
+
+	// offline: only what is in the cache
+	appCtx.Offline = true
+	_, err, _ = r.DownloadResolvedCoord(lc)
+	assert.NoError(t, err)
+	_, err = r.ResolveCoord(coord("1"))
+	assert.ErrorContains(t, err, "offline")
+	_, err, _ = r.DownloadResolvedCoord(&model.LockedCoord{Server: lc.Server, Owner: lc.Owner, Repo: lc.Repo, Version: model.NewVersion("2.0.0")})
+	assert.ErrorContains(t, err, "offline")
+	appCtx.Offline = false
+
+	// other servers are left to other resolvers
+	fc, _ = model.NewCoordFromStr("other.example.com/owner/repo@1")
+	lc, err = r.ResolveCoord(fc)
+	assert.NoError(t, err)
+	assert.Nil(t, lc)
+}
@@ -5,9 +5,7 @@ package resolver
 
 import (
 	"fmt"
-	"runtime"
 
-	"github.com/Masterminds/semver"
 	"github.com/bazurto/bz/lib/model"
 )
 
@@ -18,22 +16,49 @@ type Resolver interface {
 	DownloadResolvedCoord(c *model.LockedCoord) (string, error, bool)
 }
 
-func possibleAssetNames(c *model.LockedCoord) []BzAsset {
-	osArch := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+// VersionLister is implemented by resolvers that can list the versions
+// available for a dependency
+type VersionLister interface {
+	// ListVersions returns every version available for c.  The bool is false
+	// when c is not handled by the resolver
+	ListVersions(c *model.FuzzyCoord) ([]model.Version, bool, error)
+}
+
+// PlatformLocker is implemented by resolvers that can lock the assets of
+// other platforms without downloading them
+type PlatformLocker interface {
+	// LockPlatforms sets lc.Platforms to the asset of every platform.  The
+	// bool is false when lc is not handled by the resolver
+	LockPlatforms(lc *model.LockedCoord, platforms []model.Platform) (bool, error)
+}
 
-	extensions := []string{"zip", "tgz", "tar.gz"} // possible extensions
+// assetExtensions are the possible extensions of assets in priority order
+var assetExtensions = []string{"zip", "tgz", "tar.gz"}
 
+// possibleAssetNames returns the asset names of c for platform in priority order
+func possibleAssetNames(c *model.LockedCoord, platform model.Platform) []BzAsset {
 	var res []BzAsset
-	for _, ext := range extensions {
+	for _, ext := range assetExtensions {
 		res = append(res,
-			BzAsset{Canonical: fmt.Sprintf("%s-%s-v%s", c.Repo, osArch, c.Version.Canonical()), Ext: ext}, // openjdk-linux-amd64-v1.2.3.zip
-			BzAsset{Canonical: fmt.Sprintf("%s-v%s", c.Repo, c.Version.Canonical()), Ext: ext},            // openjdk-v1.2.3.zip
-			BzAsset{Canonical: c.Repo, Ext: ext},                                                          // openjdk.zip
+			BzAsset{Canonical: AssetCanonicalName(c.Repo, &platform, c.Version.Canonical()), Ext: ext}, // openjdk-linux-amd64-v1.2.3.zip
+			BzAsset{Canonical: AssetCanonicalName(c.Repo, nil, c.Version.Canonical()), Ext: ext},       // openjdk-v1.2.3.zip
+			BzAsset{Canonical: c.Repo, Ext: ext},                                                       // openjdk.zip
 		)
 	}
 	return res
 }
 
+// AssetCanonicalName returns the name of a release asset without extension.
+// Without platform the asset is for every platform.  e.g.:
+// - openjdk-linux-amd64-v1.2.3
+// - openjdk-v1.2.3
+func AssetCanonicalName(name string, platform *model.Platform, version string) string {
+	if platform == nil {
+		return fmt.Sprintf("%s-v%s", name, version)
+	}
+	return fmt.Sprintf("%s-%s-v%s", name, platform, version)
+}
+
 type BzAsset struct {
 	Ext       string // zip
 	Canonical string // project-name-linux-amd64-v1.2.3
@@ -52,18 +77,3 @@ func (o BzAssetArrHelper) CollectNames() []string {
 	}
 	return names
 }
-
-func versionCompare(a, b string) int {
-	v1, err := semver.NewVersion(a)
-	if err != nil {
-		Warn.Printf("invalid semver `%s`", a)
-		return 0
-	}
-	v2, err := semver.NewVersion(b)
-	if err != nil {
-		Warn.Printf("invalid semver `%s`", b)
-		return 0
-	}
-
-	return v1.Compare(v2)
-}
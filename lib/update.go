@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bazurto/bz/lib/model"
+	"github.com/bazurto/bz/lib/utils"
+)
+
+// Update re-resolves the dependencies in names (server/owner/repo) keeping
+// every other dependency pinned to the version in the lock file.  When names
+// is empty all dependencies are updated.
+//
+// With major, dependencies are updated to the latest release even if it does
+// not match the version in the configuration file, and the configuration file
+// is rewritten with the new major version when it does not match: python@2 =>
+// python@3.  The configuration file is restored if the lock cannot be updated
+func (o *Engine) Update(dir string, names []string, major bool) ([]CoordChange, error) {
+	if o.appCtx.Frozen {
+		return nil, fmt.Errorf("dependencies cannot be updated in frozen mode")
+	}
+
+	status, err := o.lockStatus(dir)
+	if err != nil {
+		return nil, err
+	}
+	if status.Config == nil {
+		return nil, fmt.Errorf("no configuration file found in %s", dir)
+	}
+
+	// dependencies to update
+	selected := make(map[string]string) // canonical name => dep string in config
+	for _, dep := range status.Config.Deps {
+		fc, err := newFuzzyCoord(dep, status.Config, o.appCtx.Prerelease)
+		if err != nil {
+			return nil, err
+		}
+		selected[fc.CanonicalNameNoVersion()] = dep
+	}
+	if len(names) > 0 {
+		all := selected
+		selected = make(map[string]string)
+		for _, name := range names {
+			name = strings.SplitN(name, "@", 2)[0]
+			dep, ok := all[name]
+			if !ok {
+				return nil, fmt.Errorf("`%s` is not a dependency in %s", name, status.ConfigFile)
+			}
+			selected[name] = dep
+		}
+	}
+
+	// major: resolve latest and rewrite the configuration when the latest
+	// release does not match the version in it
+	latest := make(map[string]*model.LockedCoord)
+	rewrites := make(map[string]string) // dep string => new dep string
+	if major {
+		for name, dep := range selected {
+			fc, _ := newFuzzyCoord(dep, status.Config, o.appCtx.Prerelease)
+			if fc.Server == "local" || fc.Server == "local.local" {
+				continue
+			}
+
+			latestCoord := *fc
+			latestCoord.Version = ""
+			lc, err := o.resolveCoord(&latestCoord)
+			if err != nil {
+				return nil, err
+			}
+			latest[name] = lc
+
+			if !fc.Constraint().Matches(lc.Version) {
+				rewrites[dep] = fmt.Sprintf("%s@%d", name, lc.Version.Major())
+			}
+		}
+	}
+
+	return o.editConfigAndRelock(dir, status, selected, latest, func() error {
+		for dep, newDep := range rewrites {
+			Info.Printf("%s: %s -> %s", status.ConfigFile, dep, newDep)
+			if err := utils.ReplaceConfigDep(status.ConfigFile, dep, newDep); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// relock resolves the configuration in dir and rewrites the lock file.  The
+// dependencies in resolve (canonical names) are resolved again, the ones in
+// pinned are locked to the given coord and every other dependency keeps the
+// version in oldDeps when it still satisfies the configuration.
+func (o *Engine) relock(
+	dir string,
+	oldDeps []*model.LockedCoord,
+	resolve map[string]string,
+	pinned map[string]*model.LockedCoord,
+) ([]CoordChange, error) {
+	keep := func(fc *model.FuzzyCoord) *model.LockedCoord {
+		name := fc.CanonicalNameNoVersion()
+		if lc, ok := pinned[name]; ok {
+			return lc
+		}
+		if _, ok := resolve[name]; ok {
+			return nil // resolve
+		}
+		for _, lc := range oldDeps {
+			if sameDependency(fc, lc) && lockedCoordSatisfies(fc, lc) {
+				return lc
+			}
+		}
+		return nil // not locked yet
+	}
+	lcc, err := o.readFuzzyConfigContentFromDirKeeping(dir, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.writeLock(dir, lcc); err != nil {
+		return nil, err
+	}
+
+	return diffLockedCoords(oldDeps, lcc.Deps), nil
+}
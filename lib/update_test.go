@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateMajor(t *testing.T) {
+	// the configuration is only rewritten when latest does not match
+	d := []struct {
+		config   string
+		expected string
+		changes  []string
+	}{
+		{`deps = ["example.com/o/c@1.2"]`, `deps = ["example.com/o/c@1"]`, []string{"~ example.com/o/c 1.2.0 -> 1.3.0"}},
+		{`deps = ["example.com/o/c@^1.0"]`, `deps = ["example.com/o/c@^1.0"]`, nil},
+	}
+	for _, test := range d {
+		engine, project := conflictsTestEngine(t, test.config)
+		_, err := engine.Lock(project)
+		assert.NoError(t, err, test.config)
+
+		changes, err := engine.Update(project, nil, true)
+		assert.NoError(t, err, test.config)
+		var result []string
+		for _, c := range changes {
+			result = append(result, c.String())
+		}
+		assert.Equal(t, test.changes, result, test.config)
+		b, _ := os.ReadFile(filepath.Join(project, ".bz.hcl"))
+		assert.Equal(t, test.expected, string(b), test.config)
+	}
+}
+
+func TestUpdateRestoresConfig(t *testing.T) {
+	// a@1 requires c@1.2.0, c@1.3.0 conflicts with it
+	config := `conflicts = "fail"` + "\n" + `deps = ["example.com/o/c@1.2", "example.com/o/a@1"]`
+	engine, project := conflictsTestEngine(t, config)
+	_, err := engine.Lock(project)
+	assert.NoError(t, err)
+
+	_, err = engine.Update(project, []string{"example.com/o/c"}, true)
+	assert.Error(t, err)
+	b, _ := os.ReadFile(filepath.Join(project, ".bz.hcl"))
+	assert.Equal(t, config, string(b))
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveFiles returns the regular files in srcDir, relative to srcDir and
+// with / as separator, matching any of the include ant patterns and none of
+// the exclude patterns.  Patterns naming a directory match everything in it:
+// dir and dir/ are the same as dir/**.  No include patterns means everything
+func ArchiveFiles(srcDir string, include, exclude []string) ([]string, error) {
+	if len(include) == 0 {
+		include = []string{"**"}
+	}
+	include = dirPatterns(srcDir, include)
+	exclude = dirPatterns(srcDir, exclude)
+
+	var files []string
+	err := RecurseDir(srcDir, func(fullFilename string, file *os.File) error {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("file.Stat(%s): %w", fullFilename, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		filename, err := filepath.Rel(srcDir, fullFilename) // /path/to/srcDir/dir/file => dir/file
+		if err != nil {
+			return err
+		}
+		filename = filepath.ToSlash(filename) // replace windows filenames to *nix filenames: dir\file => dir/file
+
+		if matchesAny(include, filename) && !matchesAny(exclude, filename) {
+			files = append(files, filename)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ArchiveFiles(): %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// dirPatterns appends /** to patterns naming a directory in srcDir
+// - dirToIncude/ => dirToInclude/**
+// - dirToIncude => dirToInclude/**
+func dirPatterns(srcDir string, patterns []string) []string {
+	var fixed []string
+	for _, pattern := range patterns {
+		if stat, err := os.Stat(filepath.Join(srcDir, filepath.FromSlash(pattern))); err == nil && stat.IsDir() {
+			pattern = strings.TrimSuffix(pattern, "/") + "/**"
+		}
+		fixed = append(fixed, pattern)
+	}
+	return fixed
+}
+
+func matchesAny(patterns []string, filename string) bool {
+	for _, pattern := range patterns {
+		if antMatcher.Match(pattern, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tgz writes a gzipped tar of the files in srcDir matching the include ant
+// patterns and not matching the exclude patterns.  See ArchiveFiles
+func Tgz(srcDir string, writer io.Writer, include, exclude []string) error {
+	var err error
+	srcDir, err = filepath.Abs(srcDir) // clean path
+	if err != nil {
+		return fmt.Errorf("Tgz() filepath.Abs: %w", err)
+	}
+
+	files, err := ArchiveFiles(srcDir, include, exclude)
+	if err != nil {
+		return fmt.Errorf("Tgz(): %w", err)
+	}
+
+	gw := gzip.NewWriter(writer)
+	tw := tar.NewWriter(gw)
+	dirs := make(map[string]bool)
+	for _, filename := range files {
+		// Untgz expects the directories before the files in them
+		if err := tgzDirs(srcDir, tw, path.Dir(filename), dirs); err != nil {
+			return fmt.Errorf("Tgz(): %w", err)
+		}
+		if err := tgzFile(tw, filepath.Join(srcDir, filepath.FromSlash(filename)), filename); err != nil {
+			return fmt.Errorf("Tgz(): %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("Tgz(): %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("Tgz(): %w", err)
+	}
+	return nil
+}
+
+// tgzDirs writes the header of dir and its parents if they were not written yet
+func tgzDirs(srcDir string, tw *tar.Writer, dir string, written map[string]bool) error {
+	if dir == "." || written[dir] {
+		return nil
+	}
+	if err := tgzDirs(srcDir, tw, path.Dir(dir), written); err != nil {
+		return err
+	}
+	written[dir] = true
+
+	info, err := os.Stat(filepath.Join(srcDir, filepath.FromSlash(dir)))
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("tar.FileInfoHeader(%s): %w", dir, err)
+	}
+	header.Name = dir + "/"
+	return tw.WriteHeader(header)
+}
+
+func tgzFile(tw *tar.Writer, fullFilename, filename string) error {
+	file, err := os.Open(fullFilename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("file.Stat(%s): %w", fullFilename, err)
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("tar.FileInfoHeader(%s): %w", fullFilename, err)
+	}
+	header.Name = filename
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("tar.WriteHeader(%s): %w", filename, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("io.Copy(%s, %s): %w", filename, fullFilename, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeArchiveTestFiles(t *testing.T, files ...string) string {
+	dir := t.TempDir()
+	for _, f := range files {
+		f = filepath.Join(dir, filepath.FromSlash(f))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(f), 0755))
+		assert.Nil(t, os.WriteFile(f, []byte(f), 0755))
+	}
+	return dir
+}
+
+func TestArchiveFiles(t *testing.T) {
+	dir := writeArchiveTestFiles(t, ".bz.lock", "bin/tool", "bin/linux-amd64/tool", "docs/README.md", "dist/old.tgz")
+
+	files, err := ArchiveFiles(dir, nil, []string{"dist"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{".bz.lock", "bin/linux-amd64/tool", "bin/tool", "docs/README.md"}, files)
+
+	files, err = ArchiveFiles(dir, []string{".bz.lock", "bin/"}, []string{"**/linux-amd64/**"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{".bz.lock", "bin/tool"}, files)
+}
+
+func TestTgz(t *testing.T) {
+	dir := writeArchiveTestFiles(t, ".bz.lock", "bin/tool", "lib/a/b.txt")
+
+	archive := filepath.Join(t.TempDir(), "pkg.tgz")
+	w, err := os.Create(archive)
+	assert.Nil(t, err)
+	assert.Nil(t, Tgz(dir, w, nil, nil))
+	assert.Nil(t, w.Close())
+
+	extracted := filepath.Join(t.TempDir(), "extracted")
+	assert.Nil(t, Untgz(archive, extracted))
+	assert.FileExists(t, filepath.Join(extracted, ".bz.lock"))
+	assert.FileExists(t, filepath.Join(extracted, "lib", "a", "b.txt"))
+	stat, err := os.Stat(filepath.Join(extracted, "bin", "tool"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+}
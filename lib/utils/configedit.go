@@ -0,0 +1,350 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// The functions in this file edit the `deps` attribute of HCL and JSON
+// configuration files as text.  Everything else in the file, including
+// comments and ordering, is left untouched.
+
+// ReplaceConfigDep replaces the dependency string oldDep with newDep in the
+// configuration file f
+func ReplaceConfigDep(f, oldDep, newDep string) error {
+	return editConfigDeps(f, func(b []byte, list *depsList) ([]byte, error) {
+		i := list.index(oldDep)
+		if i < 0 {
+			return nil, fmt.Errorf("dependency `%s` not found", oldDep)
+		}
+		e := list.elems[i]
+		return splice(b, e.start, e.end, list.quote(newDep)), nil
+	})
+}
+
+// AddConfigDep appends dep to the dependencies in the configuration file f.
+// The `deps` attribute is created if it does not exist
+func AddConfigDep(f, dep string) error {
+	return editConfigDeps(f, func(b []byte, list *depsList) ([]byte, error) {
+		if list.index(dep) >= 0 {
+			return nil, fmt.Errorf("dependency `%s` already exists", dep)
+		}
+		return list.add(b, dep), nil
+	})
+}
+
+// RemoveConfigDep removes dep from the dependencies in the configuration file f
+func RemoveConfigDep(f, dep string) error {
+	return editConfigDeps(f, func(b []byte, list *depsList) ([]byte, error) {
+		i := list.index(dep)
+		if i < 0 {
+			return nil, fmt.Errorf("dependency `%s` not found", dep)
+		}
+		return list.remove(b, i), nil
+	})
+}
+
+func editConfigDeps(f string, edit func(b []byte, list *depsList) ([]byte, error)) error {
+	b, err := os.ReadFile(f)
+	if err != nil {
+		return fmt.Errorf("editConfigDeps(): %w", err)
+	}
+
+	var list *depsList
+	if IsIonFile(f) {
+		list, err = jsonDepsList(b)
+	} else {
+		list, err = hclDepsList(f, b)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", f, err)
+	}
+
+	out, err := edit(b, list)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f, err)
+	}
+	return writeFileKeepMode(f, out)
+}
+
+// depsList is the position of the `deps` list and its elements in a
+// configuration file
+type depsList struct {
+	json  bool      // json or hcl
+	found bool      // false when there is no `deps` attribute
+	open  int       // position of `[`, or where to insert the attribute when not found
+	close int       // position of `]`
+	elems []depElem // string elements
+}
+
+type depElem struct {
+	start, end int // quoted string including quotes
+	value      string
+}
+
+func (l *depsList) index(dep string) int {
+	for i, e := range l.elems {
+		if e.value == dep {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *depsList) quote(s string) []byte {
+	if l.json {
+		b, _ := json.Marshal(s)
+		return b
+	}
+	return []byte(HclQuote(s))
+}
+
+func (l *depsList) add(b []byte, dep string) []byte {
+	quoted := string(l.quote(dep))
+	if !l.found {
+		if l.json {
+			sep := "," // before the next key
+			if rest := bytes.TrimSpace(b[l.open:]); len(rest) > 0 && rest[0] == '}' {
+				sep = "" // empty object
+			} else if l.open < len(b) && b[l.open] != '\n' {
+				sep = ", "
+			}
+			return splice(b, l.open, l.open, []byte(fmt.Sprintf("\n  \"deps\": [%s]%s", quoted, sep)))
+		}
+		return splice(b, l.open, l.open, []byte(fmt.Sprintf("deps = [\n  %s,\n]\n", quoted)))
+	}
+
+	// one element per line: add a line with the same indentation as the last element
+	if bytes.ContainsRune(b[l.open:l.close], '\n') {
+		indent := "  "
+		insertAt := lineStart(b, l.close)
+		if len(l.elems) > 0 {
+			last := l.elems[len(l.elems)-1]
+			indent = string(b[lineStart(b, last.start):last.start])
+			if strings.TrimSpace(indent) != "" {
+				indent = "  " // several elements in a line
+			}
+			if !hasTrailingComma(b, last.end, l.close) {
+				b = splice(b, last.end, last.end, []byte(","))
+				insertAt++
+			}
+		}
+		if l.json {
+			return splice(b, insertAt, insertAt, []byte(fmt.Sprintf("%s%s\n", indent, quoted))) // no trailing comma in JSON
+		}
+		return splice(b, insertAt, insertAt, []byte(fmt.Sprintf("%s%s,\n", indent, quoted)))
+	}
+
+	// one line: [a, b] => [a, b, c]
+	if len(l.elems) == 0 {
+		return splice(b, l.open+1, l.close, []byte(quoted))
+	}
+	last := l.elems[len(l.elems)-1]
+	if hasTrailingComma(b, last.end, l.close) {
+		return splice(b, l.close, l.close, []byte(fmt.Sprintf(" %s,", quoted)))
+	}
+	return splice(b, last.end, last.end, []byte(fmt.Sprintf(", %s", quoted)))
+}
+
+func (l *depsList) remove(b []byte, i int) []byte {
+	e := l.elems[i]
+
+	// alone in its line: remove the whole line including comments
+	start := lineStart(b, e.start)
+	end := bytes.IndexByte(b[e.end:], '\n')
+	if end >= 0 {
+		end += e.end + 1
+	}
+	alone := strings.TrimSpace(string(b[start:e.start])) == "" &&
+		end >= 0 && end <= l.close &&
+		(i == len(l.elems)-1 || l.elems[i+1].start >= end)
+	if alone {
+		// JSON does not allow a trailing comma after the new last element
+		if l.json && i == len(l.elems)-1 && i > 0 && !hasTrailingComma(b, e.end, l.close) {
+			prev := l.elems[i-1]
+			comma := prev.end + bytes.IndexByte(b[prev.end:], ',')
+			b = splice(b, comma, comma+1, nil)
+			start--
+			end--
+		}
+		return splice(b, start, end, nil)
+	}
+
+	// remove the element, the comma and spaces after it
+	end = e.end
+	for end < l.close && (b[end] == ' ' || b[end] == '\t') {
+		end++
+	}
+	if end < l.close && b[end] == ',' {
+		end++
+		for end < l.close && (b[end] == ' ' || b[end] == '\t') {
+			end++
+		}
+		return splice(b, e.start, end, nil)
+	}
+
+	// last element without trailing comma: remove the comma before it
+	start = e.start
+	if i > 0 {
+		start = l.elems[i-1].end
+	}
+	return splice(b, start, e.end, nil)
+}
+
+// hclDepsList finds the `deps` list in an HCL configuration
+func hclDepsList(f string, b []byte) (*depsList, error) {
+	file, diags := hclsyntax.ParseConfig(b, f, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected HCL body")
+	}
+
+	attr, ok := body.Attributes["deps"]
+	if !ok {
+		return &depsList{open: 0}, nil
+	}
+	tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr)
+	if !ok {
+		return nil, fmt.Errorf("`deps` is not a list")
+	}
+
+	list := &depsList{
+		found: true,
+		open:  tuple.SrcRange.Start.Byte,
+		close: tuple.SrcRange.End.Byte - 1,
+	}
+	for _, expr := range tuple.Exprs {
+		v, diags := expr.Value(nil)
+		if diags.HasErrors() || v.Type() != cty.String || v.IsNull() {
+			return nil, fmt.Errorf("`deps` has to be a list of strings")
+		}
+		list.elems = append(list.elems, depElem{
+			start: expr.Range().Start.Byte,
+			end:   expr.Range().End.Byte,
+			value: v.AsString(),
+		})
+	}
+	return list, nil
+}
+
+// jsonDepsList finds the `deps` list in a JSON/ION configuration
+func jsonDepsList(b []byte) (*depsList, error) {
+	start, end, err := jsonDepsRange(b)
+	if errors.Is(err, errDepsNotFound) {
+		// the configuration is an object, insert deps first
+		return &depsList{json: true, open: bytes.IndexByte(b, '{') + 1}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	list := &depsList{json: true, found: true, open: start, close: end - 1}
+	for i := start + 1; i < end-1; i++ {
+		if b[i] != '"' {
+			continue
+		}
+		j := i + 1
+		for ; j < end-1 && b[j] != '"'; j++ {
+			if b[j] == '\\' {
+				j++
+			}
+		}
+		var value string
+		if err := json.Unmarshal(b[i:j+1], &value); err != nil {
+			return nil, fmt.Errorf("`deps`: %w", err)
+		}
+		list.elems = append(list.elems, depElem{start: i, end: j + 1, value: value})
+		i = j
+	}
+	return list, nil
+}
+
+// errDepsNotFound is returned by jsonDepsRange when the configuration has no deps
+var errDepsNotFound = errors.New("`deps` not found")
+
+// jsonDepsRange returns the position of the `[` and `]` of the deps array
+// of the top level object in a JSON configuration.  end points right after
+// `]`.  deps keys of nested objects, e.g. in env, are ignored
+func jsonDepsRange(b []byte) (int, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return 0, 0, fmt.Errorf("configuration is not an object")
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return 0, 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if key != "deps" {
+			continue
+		}
+		if len(value) == 0 || value[0] != '[' {
+			return 0, 0, fmt.Errorf("`deps` is not a list")
+		}
+		// the decoder stops right after the value
+		end := int(dec.InputOffset())
+		return end - len(value), end, nil
+	}
+	return 0, 0, errDepsNotFound
+}
+
+// HclQuote quotes s as an HCL string literal without template interpolation
+func HclQuote(s string) string {
+	b, _ := json.Marshal(s)
+	q := strings.ReplaceAll(string(b), "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+// hasTrailingComma returns true if there is a comma between pos and end,
+// ignoring spaces and comments
+func hasTrailingComma(b []byte, pos, end int) bool {
+	for i := pos; i < end; i++ {
+		switch b[i] {
+		case ' ', '\t', '\r', '\n':
+		case ',':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// lineStart returns the position of the first char in the line of pos
+func lineStart(b []byte, pos int) int {
+	return bytes.LastIndexByte(b[:pos], '\n') + 1
+}
+
+// splice replaces b[start:end] with s
+func splice(b []byte, start, end int, s []byte) []byte {
+	var out []byte
+	out = append(out, b[:start]...)
+	out = append(out, s...)
+	out = append(out, b[end:]...)
+	return out
+}
+
+func writeFileKeepMode(f string, b []byte) error {
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(f); err == nil {
+		mode = stat.Mode()
+	}
+	return os.WriteFile(f, b, mode)
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 RH America LLC <info@rhamerica.com>
+// SPDX-License-Identifier: GPL-3.0-only
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	f := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(f, []byte(content), 0644))
+	return f
+}
+
+func readTestFile(t *testing.T, f string) string {
+	b, err := os.ReadFile(f)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestReplaceConfigDepHcl(t *testing.T) {
+	f := writeTestFile(t, ".bz.hcl", `# deps
+deps = [
+  "github.com/bazurto/python@2", # python
+  "github.com/bazurto/groovy",
+]
+`)
+	assert.Nil(t, ReplaceConfigDep(f, "github.com/bazurto/python@2", "github.com/bazurto/python@3"))
+	assert.Equal(t, `# deps
+deps = [
+  "github.com/bazurto/python@3", # python
+  "github.com/bazurto/groovy",
+]
+`, readTestFile(t, f))
+
+	assert.NotNil(t, ReplaceConfigDep(f, "github.com/bazurto/python@2", "github.com/bazurto/python@3"))
+}
+
+func TestReplaceConfigDepJson(t *testing.T) {
+	f := writeTestFile(t, ".bz.json", `{
+  "env": {"A": "github.com/bazurto/python@2"},
+  "deps": ["github.com/bazurto/python@2"]
+}
+`)
+	assert.Nil(t, ReplaceConfigDep(f, "github.com/bazurto/python@2", "github.com/bazurto/python@3"))
+	assert.Equal(t, `{
+  "env": {"A": "github.com/bazurto/python@2"},
+  "deps": ["github.com/bazurto/python@3"]
+}
+`, readTestFile(t, f))
+}
+
+func TestAddConfigDepHcl(t *testing.T) {
+	f := writeTestFile(t, ".bz.hcl", `# deps
+deps = [
+  "github.com/bazurto/python@3" # python
+]
+env = { A = "B" }
+`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `# deps
+deps = [
+  "github.com/bazurto/python@3", # python
+  "github.com/bazurto/groovy@4",
+]
+env = { A = "B" }
+`, readTestFile(t, f))
+	assert.NotNil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+
+	f = writeTestFile(t, ".bz.hcl", `deps = ["github.com/bazurto/python@3"]
+`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `deps = ["github.com/bazurto/python@3", "github.com/bazurto/groovy@4"]
+`, readTestFile(t, f))
+
+	f = writeTestFile(t, ".bz.hcl", `env = { A = "B" }
+`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `deps = [
+  "github.com/bazurto/groovy@4",
+]
+env = { A = "B" }
+`, readTestFile(t, f))
+}
+
+func TestRemoveConfigDepHcl(t *testing.T) {
+	f := writeTestFile(t, ".bz.hcl", `deps = [
+  # python
+  "github.com/bazurto/python@3", # comment
+  "github.com/bazurto/groovy@4",
+]
+`)
+	assert.Nil(t, RemoveConfigDep(f, "github.com/bazurto/python@3"))
+	assert.Equal(t, `deps = [
+  # python
+  "github.com/bazurto/groovy@4",
+]
+`, readTestFile(t, f))
+	assert.NotNil(t, RemoveConfigDep(f, "github.com/bazurto/python@3"))
+
+	f = writeTestFile(t, ".bz.hcl", `deps = ["github.com/bazurto/python@3", "github.com/bazurto/groovy@4"]
+`)
+	assert.Nil(t, RemoveConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `deps = ["github.com/bazurto/python@3"]
+`, readTestFile(t, f))
+	assert.Nil(t, RemoveConfigDep(f, "github.com/bazurto/python@3"))
+	assert.Equal(t, `deps = []
+`, readTestFile(t, f))
+}
+
+func TestAddRemoveConfigDepJson(t *testing.T) {
+	f := writeTestFile(t, ".bz.json", `{
+  "deps": [
+    "github.com/bazurto/python@3"
+  ]
+}
+`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `{
+  "deps": [
+    "github.com/bazurto/python@3",
+    "github.com/bazurto/groovy@4"
+  ]
+}
+`, readTestFile(t, f))
+
+	assert.Nil(t, RemoveConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `{
+  "deps": [
+    "github.com/bazurto/python@3"
+  ]
+}
+`, readTestFile(t, f))
+
+	f = writeTestFile(t, ".bz.json", `{"env": {}}`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `{
+  "deps": ["github.com/bazurto/groovy@4"], "env": {}}`, readTestFile(t, f))
+
+	f = writeTestFile(t, ".bz.json", `{}`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `{
+  "deps": ["github.com/bazurto/groovy@4"]}`, readTestFile(t, f))
+}
+
+func TestJsonDepsRange(t *testing.T) {
+	// deps in nested objects and strings are not the deps of the configuration
+	b := []byte(`{"env": {"deps": ["x"]}, "alias": {"a": "echo \"deps\": [1]"}, "deps" : [ "github.com/bazurto/python@3" ], "x": []}`)
+	start, end, err := jsonDepsRange(b)
+	assert.Nil(t, err)
+	assert.Equal(t, `[ "github.com/bazurto/python@3" ]`, string(b[start:end]))
+
+	_, _, err = jsonDepsRange([]byte(`{"env": {"deps": ["x"]}}`))
+	assert.ErrorIs(t, err, errDepsNotFound)
+	_, _, err = jsonDepsRange([]byte(`{"deps": "x"}`))
+	assert.ErrorContains(t, err, "not a list")
+	_, _, err = jsonDepsRange([]byte(`["deps"]`))
+	assert.ErrorContains(t, err, "not an object")
+
+	f := writeTestFile(t, ".bz.json", `{"env": {"deps": "x"}}`)
+	assert.Nil(t, AddConfigDep(f, "github.com/bazurto/groovy@4"))
+	assert.Equal(t, `{
+  "deps": ["github.com/bazurto/groovy@4"], "env": {"deps": "x"}}`, readTestFile(t, f))
+}
+
+func TestHclQuote(t *testing.T) {
+	assert.Equal(t, `"$DIR/bin"`, HclQuote("$DIR/bin"))
+	assert.Equal(t, `"$${HOME} \"x\""`, HclQuote(`${HOME} "x"`))
+}
@@ -4,6 +4,9 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -21,3 +24,18 @@ func GetCurrentDir() string {
 	dir, _ := os.Getwd()
 	return FsAbs(dir)
 }
+
+// FileSha256 returns the hex encoded sha256 digest of the content of f
+func FileSha256(f string) (string, error) {
+	r, err := os.Open(f)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
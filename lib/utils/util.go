@@ -116,84 +116,59 @@ func Unzip(zipFileName, dstDirName string) error {
 	return nil
 }
 
-// Zip zips up files
-func Zip(srcDir string, writer io.Writer, include []string) error {
+// Zip zips up the files in srcDir matching the include ant patterns and not
+// matching the exclude patterns.  See ArchiveFiles
+func Zip(srcDir string, writer io.Writer, include, exclude []string) error {
 	var err error
 	srcDir, err = filepath.Abs(srcDir) // clean path
 	if err != nil {
 		return fmt.Errorf("Zip() filepath.Abs: %w", err)
 	}
 
-	// fixed patterns by appendding /** to dir include names
-	// - dirToIncude/ => dirToInclude/**
-	// - dirToIncude => dirToInclude/**
-	var fixedPatterns []string
-	for _, pattern := range include {
-		stat, err := os.Stat(pattern)
-		if err == nil {
-			if stat.IsDir() {
-				if strings.HasSuffix(pattern, "/") { //pattern ends with /, then append **
-					pattern = pattern + "**"
-				} else { // does not end in /, append /**
-					pattern = pattern + "/**"
-				}
-			}
-		}
-		fixedPatterns = append(fixedPatterns, pattern)
+	files, err := ArchiveFiles(srcDir, include, exclude)
+	if err != nil {
+		return fmt.Errorf("Zip(): %w", err)
 	}
-	fmt.Printf("%v\n", fixedPatterns)
 
 	tw := zip.NewWriter(writer)
-	defer tw.Close()
-
-	err = RecurseDir(srcDir, func(fullFilename string, file *os.File) error {
-		filename := strings.Replace(fullFilename, srcDir, "", 1) // /path/to/srcDir/dir/file => /dir/file
-		filename = strings.TrimLeft(filename, "/")               // dir/file
-		filename = filepath.ToSlash(filename)                    // replace windows filenames to *nix filenames: dir\file => dir/file
-
-		includeFile := false
-		for _, pattern := range fixedPatterns {
-			includeFile = antMatcher.Match(pattern, filename)
-			//includeFile, _ = path.Match(pattern, filename)
-			if includeFile {
-				break
+	for _, filename := range files {
+		fullFilename := filepath.Join(srcDir, filepath.FromSlash(filename))
+		err := func() error {
+			file, err := os.Open(fullFilename)
+			if err != nil {
+				return err
 			}
-		}
-		if !includeFile {
-			return nil
-		}
-		fmt.Printf("- %s\n", filename)
+			defer file.Close()
 
-		// Get FileInfo about our file providing file size, mode, etc.
-		info, err := file.Stat()
-		if err != nil {
-			return fmt.Errorf("file.Stat(%s): %w", fullFilename, err)
-		}
-
-		// Create a tar Header from the FileInfo data
-		header, err := zip.FileInfoHeader(info)
-		if err != nil {
-			return fmt.Errorf("zip.FileInfoHeader(%s): %w", fullFilename, err)
-		}
-		header.Name = filename
+			// Get FileInfo about our file providing file size, mode, etc.
+			info, err := file.Stat()
+			if err != nil {
+				return fmt.Errorf("file.Stat(%s): %w", fullFilename, err)
+			}
 
-		// Write file header to the tar archive
-		w, err := tw.CreateHeader(header)
-		if err != nil {
-			return fmt.Errorf("zip.CreateHeader(%s): %w", filename, err)
-		}
+			// Create a zip Header from the FileInfo data
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return fmt.Errorf("zip.FileInfoHeader(%s): %w", fullFilename, err)
+			}
+			header.Name = filename
+			header.Method = zip.Deflate
 
-		// Copy contents if it is regular file
-		if info.Mode().IsRegular() {
-			_, err = io.Copy(w, file)
+			// Write file header to the zip archive
+			w, err := tw.CreateHeader(header)
 			if err != nil {
+				return fmt.Errorf("zip.CreateHeader(%s): %w", filename, err)
+			}
+			if _, err = io.Copy(w, file); err != nil {
 				return fmt.Errorf("io.Copy(%s, %s): %w", filename, fullFilename, err)
 			}
+			return nil
+		}()
+		if err != nil {
+			return fmt.Errorf("Zip(): %w", err)
 		}
-
-		return nil
-	})
-	if err != nil {
+	}
+	if err := tw.Close(); err != nil {
 		return fmt.Errorf("Zip(): %w", err)
 	}
 	return nil